@@ -0,0 +1,104 @@
+package accounts
+
+import (
+	"backend-api/models"
+	"backend-api/storage"
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+// purgeBatchSize is the number of accounts purged per database round trip
+const purgeBatchSize = 100
+
+// Purger permanently deletes accounts that were deleted longer than the restore
+// window ago, together with their photos and the stored photo files.
+type Purger struct {
+	users         models.UserRepository
+	photos        models.PhotoRepository
+	storage       storage.Storage
+	restoreWindow time.Duration
+}
+
+// NewPurger returns a Purger for accounts deleted more than restoreWindow ago
+func NewPurger(users models.UserRepository, photos models.PhotoRepository, store storage.Storage, restoreWindow time.Duration) *Purger {
+	return &Purger{users: users, photos: photos, storage: store, restoreWindow: restoreWindow}
+}
+
+// Purge deletes every account whose restore window ended before now and
+// returns how many were deleted
+func (p *Purger) Purge(ctx context.Context, now time.Time) (int, error) {
+	before := now.Add(-p.restoreWindow)
+	purged := 0
+	for {
+		ids, err := p.users.DeletedUsersBefore(before, purgeBatchSize)
+		if err != nil {
+			return purged, err
+		}
+
+		for _, id := range ids {
+			photos, err := p.users.PurgeUser(id, before)
+			if errors.Is(err, models.ErrNotFound) {
+				// Restored or purged by another instance in the meantime
+				continue
+			}
+			if err != nil {
+				return purged, err
+			}
+			purged++
+
+			// The rows are gone; a file that cannot be removed is only logged.
+			// Only the user's own uploads are removed, and only once no other
+			// photo row references them.
+			for _, photo := range photos {
+				key, ok := p.storage.KeyFromURL(photo.PhotoURL)
+				if !ok || !storage.IsPhotoKeyOf(key, id) {
+					continue
+				}
+				refs, err := p.photos.CountPhotosByURL(photo.PhotoURL)
+				if err != nil {
+					log.Printf("Error checking references to stored photo %s of purged user ID %d: %v", key, id, err)
+					continue
+				}
+				if refs > 0 {
+					continue
+				}
+				if err := p.storage.Delete(ctx, key); err != nil {
+					log.Printf("Error deleting stored photo %s of purged user ID %d: %v", key, id, err)
+				}
+			}
+		}
+
+		if len(ids) < purgeBatchSize {
+			return purged, nil
+		}
+	}
+}
+
+// Start purges accounts now and then every interval until ctx is done. The
+// returned channel is closed once the worker has stopped.
+func (p *Purger) Start(ctx context.Context, interval time.Duration) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			purged, err := p.Purge(ctx, time.Now())
+			if err != nil {
+				log.Printf("Error purging deleted accounts: %v", err)
+			}
+			if purged > 0 {
+				log.Printf("Purged %d deleted accounts", purged)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return done
+}
@@ -0,0 +1,76 @@
+// Package accounts keeps track of the status of user accounts: a cache that
+// lets every authenticated request check whether its user is still active, and
+// the job that purges deleted accounts once their restore window has passed.
+package accounts
+
+import (
+	"backend-api/app"
+	"backend-api/models"
+	"errors"
+	"sync"
+	"time"
+)
+
+// maxCachedStatuses bounds the cache; expired entries are dropped when it is full
+const maxCachedStatuses = 10000
+
+// StatusCache caches the status of users for ttl, so that checking it on every
+// request does not hit the database. A status changed on another instance is
+// seen within ttl; changes made through this instance should call Forget.
+type StatusCache struct {
+	users   models.UserRepository
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[uint]statusEntry
+}
+
+type statusEntry struct {
+	status    app.UserStatus
+	expiresAt time.Time
+}
+
+// NewStatusCache returns a StatusCache that reads statuses from users
+func NewStatusCache(users models.UserRepository, ttl time.Duration) *StatusCache {
+	return &StatusCache{users: users, ttl: ttl, entries: map[uint]statusEntry{}}
+}
+
+// UserStatus returns the status of userID. Users that no longer exist are
+// reported as deleted.
+func (c *StatusCache) UserStatus(userID uint) (app.UserStatus, error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[userID]
+	c.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.status, nil
+	}
+
+	status := app.UserDeleted
+	user, err := c.users.GetUserByID(userID)
+	switch {
+	case err == nil:
+		status = user.Status()
+	case !errors.Is(err, models.ErrNotFound):
+		return "", err
+	}
+
+	c.mu.Lock()
+	if len(c.entries) >= maxCachedStatuses {
+		for id, entry := range c.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(c.entries, id)
+			}
+		}
+	}
+	c.entries[userID] = statusEntry{status: status, expiresAt: now.Add(c.ttl)}
+	c.mu.Unlock()
+	return status, nil
+}
+
+// Forget drops the cached status of userID so that the next check reads it again
+func (c *StatusCache) Forget(userID uint) {
+	c.mu.Lock()
+	delete(c.entries, userID)
+	c.mu.Unlock()
+}
@@ -0,0 +1,144 @@
+// Package apierror defines the errors returned by the API. Every error has a
+// stable, machine-readable code and is written as an RFC 7807 problem details
+// document (application/problem+json).
+package apierror
+
+import (
+	"backend-api/database"
+	"backend-api/models"
+	"backend-api/validation"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+)
+
+// Code identifies the kind of an error. Codes are part of the API contract and
+// must not change once published.
+type Code string
+
+// Error is an API error
+type Error struct {
+	Status int
+	Code   Code
+	// Detail is a human-readable explanation sent to the client
+	Detail string
+	// Fields lists the invalid fields of a VALIDATION_FAILED error
+	Fields validation.Errors
+	// Err is the underlying error. It is logged, never sent to the client.
+	Err error
+}
+
+// New creates an Error
+func New(status int, code Code, detail string) *Error {
+	return &Error{Status: status, Code: code, Detail: detail}
+}
+
+// Internal creates a 500 error caused by err. From still maps known model
+// errors in err, e.g. models.ErrNotFound, to their own API errors.
+func Internal(detail string, err error) *Error {
+	return &Error{Status: http.StatusInternalServerError, Code: CodeInternal, Detail: detail, Err: err}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return string(e.Code) + ": " + e.Detail + ": " + e.Err.Error()
+	}
+	return string(e.Code) + ": " + e.Detail
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// WithDetail returns a copy of e with another detail message
+func (e *Error) WithDetail(detail string) *Error {
+	copied := *e
+	copied.Detail = detail
+	return &copied
+}
+
+// Wrap returns a copy of e caused by err
+func (e *Error) Wrap(err error) *Error {
+	copied := *e
+	copied.Err = err
+	return &copied
+}
+
+// From converts err to an API error. Errors returned by the models and the
+// database drivers are mapped to their API counterparts; any other error
+// becomes INTERNAL_ERROR.
+func From(err error) *Error {
+	var apiErr *Error
+	var fieldErrs validation.Errors
+	switch {
+	case errors.As(err, &apiErr):
+		if apiErr.Code == CodeInternal && apiErr.Err != nil {
+			if cause := From(apiErr.Err); cause.Code != CodeInternal {
+				return cause
+			}
+		}
+		return apiErr
+	case errors.As(err, &fieldErrs):
+		validationErr := ErrValidation.Wrap(err)
+		validationErr.Fields = fieldErrs
+		return validationErr
+	case errors.Is(err, models.ErrNotFound), errors.Is(err, sql.ErrNoRows):
+		return ErrNotFound.Wrap(err)
+	case errors.Is(err, models.ErrProfilePhotoConflict):
+		return ErrProfilePhotoConflict.Wrap(err)
+	case errors.Is(err, models.ErrEmailTaken):
+		return ErrUserEmailTaken.Wrap(err)
+	case errors.Is(err, models.ErrUsernameTaken):
+		return ErrUserUsernameTaken.Wrap(err)
+	case errors.Is(err, models.ErrDuplicate), database.IsUniqueViolation(err):
+		return ErrConflict.Wrap(err)
+	case errors.Is(err, models.ErrRefreshTokenInvalid), errors.Is(err, models.ErrRefreshTokenReused):
+		return ErrRefreshTokenInvalid.Wrap(err)
+	case errors.Is(err, models.ErrPasswordResetTokenInvalid):
+		return ErrResetTokenInvalid.Wrap(err)
+	default:
+		return ErrInternal.Wrap(err)
+	}
+}
+
+// problem is the RFC 7807 problem details document
+type problem struct {
+	Type      string            `json:"type"`
+	Title     string            `json:"title"`
+	Status    int               `json:"status"`
+	Detail    string            `json:"detail,omitempty"`
+	Instance  string            `json:"instance,omitempty"`
+	Code      Code              `json:"code"`
+	RequestID string            `json:"request_id,omitempty"`
+	Errors    validation.Errors `json:"errors,omitempty"`
+}
+
+// requestIDHeader is set on the response by middlewares.RequestID
+const requestIDHeader = "X-Request-ID"
+
+// Write converts err with From and writes it as application/problem+json.
+// Server errors are logged together with their cause and the request ID.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	apiErr := From(err)
+	requestID := w.Header().Get(requestIDHeader)
+
+	if apiErr.Status >= http.StatusInternalServerError {
+		log.Printf("[%s] %s %s: %v", requestID, r.Method, r.URL.Path, apiErr)
+	}
+
+	body, _ := json.Marshal(problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(apiErr.Status),
+		Status:    apiErr.Status,
+		Detail:    apiErr.Detail,
+		Instance:  r.URL.Path,
+		Code:      apiErr.Code,
+		RequestID: requestID,
+		Errors:    apiErr.Fields,
+	})
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(apiErr.Status)
+	w.Write(body)
+}
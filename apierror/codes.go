@@ -0,0 +1,74 @@
+package apierror
+
+import "net/http"
+
+// Error codes returned in the "code" member of problem details
+const (
+	CodeInvalidRequest           Code = "INVALID_REQUEST"
+	CodeValidationFailed         Code = "VALIDATION_FAILED"
+	CodeRouteNotFound            Code = "ROUTE_NOT_FOUND"
+	CodeMethodNotAllowed         Code = "METHOD_NOT_ALLOWED"
+	CodeRateLimited              Code = "RATE_LIMITED"
+	CodeNotFound                 Code = "NOT_FOUND"
+	CodeConflict                 Code = "CONFLICT"
+	CodeForbidden                Code = "FORBIDDEN"
+	CodeInternal                 Code = "INTERNAL_ERROR"
+	CodeAuthTokenMissing         Code = "AUTH_TOKEN_MISSING"
+	CodeAuthTokenInvalid         Code = "AUTH_TOKEN_INVALID"
+	CodeAuthTokenRevoked         Code = "AUTH_TOKEN_REVOKED"
+	CodeInvalidCredentials       Code = "INVALID_CREDENTIALS"
+	CodeTooManyLoginAttempts     Code = "TOO_MANY_LOGIN_ATTEMPTS"
+	CodeCurrentPasswordInvalid   Code = "CURRENT_PASSWORD_INVALID"
+	CodeEmailNotVerified         Code = "EMAIL_NOT_VERIFIED"
+	CodeAccountSuspended         Code = "ACCOUNT_SUSPENDED"
+	CodeAccountDeleted           Code = "ACCOUNT_DELETED"
+	CodeAccountNotDeleted        Code = "ACCOUNT_NOT_DELETED"
+	CodeRefreshTokenInvalid      Code = "REFRESH_TOKEN_INVALID"
+	CodeVerificationTokenInvalid Code = "VERIFICATION_TOKEN_INVALID"
+	CodeResetTokenInvalid        Code = "RESET_TOKEN_INVALID"
+	CodeUserEmailTaken           Code = "USER_EMAIL_TAKEN"
+	CodeUserUsernameTaken        Code = "USER_USERNAME_TAKEN"
+	CodeUserNotFound             Code = "USER_NOT_FOUND"
+	CodePhotoNotFound            Code = "PHOTO_NOT_FOUND"
+	CodePhotoIDInvalid           Code = "PHOTO_ID_INVALID"
+	CodeProfilePhotoConflict     Code = "PROFILE_PHOTO_CONFLICT"
+	CodePhotoTooLarge            Code = "PHOTO_TOO_LARGE"
+	CodePhotoUnsupportedType     Code = "PHOTO_UNSUPPORTED_TYPE"
+)
+
+// Errors shared by the handlers. Use WithDetail or Wrap to derive a specific error.
+var (
+	ErrInvalidRequest         = New(http.StatusBadRequest, CodeInvalidRequest, "Invalid request payload")
+	ErrValidation             = New(http.StatusUnprocessableEntity, CodeValidationFailed, "Validation failed")
+	ErrRouteNotFound          = New(http.StatusNotFound, CodeRouteNotFound, "No route matches the request")
+	ErrMethodNotAllowed       = New(http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed for this route")
+	ErrRateLimited            = New(http.StatusTooManyRequests, CodeRateLimited, "Too many requests, retry later")
+	ErrNotFound               = New(http.StatusNotFound, CodeNotFound, "Resource not found")
+	ErrConflict               = New(http.StatusConflict, CodeConflict, "Resource already exists")
+	ErrForbidden              = New(http.StatusForbidden, CodeForbidden, "You do not have permission to perform this action")
+	ErrInternal               = New(http.StatusInternalServerError, CodeInternal, "Internal server error")
+	ErrAuthTokenMissing       = New(http.StatusUnauthorized, CodeAuthTokenMissing, "Missing auth token")
+	ErrAuthTokenInvalid       = New(http.StatusUnauthorized, CodeAuthTokenInvalid, "Invalid token")
+	ErrAuthTokenRevoked       = New(http.StatusUnauthorized, CodeAuthTokenRevoked, "Token has been revoked")
+	ErrInvalidCredentials     = New(http.StatusUnauthorized, CodeInvalidCredentials, "Invalid email or password")
+	ErrTooManyLoginAttempts   = New(http.StatusTooManyRequests, CodeTooManyLoginAttempts, "Too many failed login attempts, retry later")
+	ErrCurrentPasswordInvalid = New(http.StatusForbidden, CodeCurrentPasswordInvalid, "Current password is incorrect")
+	ErrEmailNotVerified       = New(http.StatusForbidden, CodeEmailNotVerified, "Email address is not verified")
+	ErrAccountSuspended       = New(http.StatusForbidden, CodeAccountSuspended, "Account is suspended")
+	ErrAccountDeleted         = New(http.StatusForbidden, CodeAccountDeleted, "Account is deleted")
+	ErrAccountNotDeleted      = New(http.StatusConflict, CodeAccountNotDeleted, "Account is not deleted")
+
+	ErrRefreshTokenInvalid      = New(http.StatusUnauthorized, CodeRefreshTokenInvalid, "Invalid refresh token")
+	ErrVerificationTokenInvalid = New(http.StatusBadRequest, CodeVerificationTokenInvalid, "Invalid or expired verification token")
+	ErrResetTokenInvalid        = New(http.StatusBadRequest, CodeResetTokenInvalid, "Invalid or expired reset token")
+
+	ErrUserEmailTaken    = New(http.StatusConflict, CodeUserEmailTaken, "Email is already registered")
+	ErrUserUsernameTaken = New(http.StatusConflict, CodeUserUsernameTaken, "Username is already taken")
+	ErrUserNotFound      = New(http.StatusNotFound, CodeUserNotFound, "User not found")
+
+	ErrPhotoNotFound        = New(http.StatusNotFound, CodePhotoNotFound, "Photo not found")
+	ErrPhotoIDInvalid       = New(http.StatusBadRequest, CodePhotoIDInvalid, "Invalid photo ID")
+	ErrProfilePhotoConflict = New(http.StatusConflict, CodeProfilePhotoConflict, "Profile photo was changed by another request, please retry")
+	ErrPhotoTooLarge        = New(http.StatusRequestEntityTooLarge, CodePhotoTooLarge, "Photo is too large")
+	ErrPhotoUnsupportedType = New(http.StatusUnsupportedMediaType, CodePhotoUnsupportedType, "Photo must be a JPEG, PNG, GIF or WebP image")
+)
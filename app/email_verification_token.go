@@ -0,0 +1,15 @@
+package app
+
+import "time"
+
+// EmailVerificationToken is emailed to prove that a user receives mail at Email.
+// It is opaque, so it cannot be mistaken for a signed access token, and only
+// its SHA-256 hash is stored.
+type EmailVerificationToken struct {
+	ID        uint      `json:"id"`
+	UserID    uint      `json:"user_id"`
+	Email     string    `json:"email"`
+	TokenHash string    `json:"-"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
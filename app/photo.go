@@ -5,7 +5,7 @@ import "time"
 // Photo represents a user's photo in the system
 type Photo struct {
 	ID        uint      `json:"id"`
-	PhotoURL  string    `json:"photo_url" binding:"required"`
+	PhotoURL  string    `json:"photo_url" binding:"required"` // checked by the photo handlers, which also accept uploaded file URLs
 	UserID    uint      `json:"user_id"`
 	IsProfile bool      `json:"is_profile"`
 	CreatedAt time.Time `json:"created_at"`
@@ -0,0 +1,52 @@
+package app
+
+// Role is the role of a user. Each role grants a fixed set of permissions.
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+// Permission is an action that requires more than being signed in
+type Permission string
+
+const (
+	PermUsersRead   Permission = "users:read"
+	PermUsersWrite  Permission = "users:write"
+	PermUsersRoles  Permission = "users:roles"
+	PermPhotosRead  Permission = "photos:read"
+	PermPhotosWrite Permission = "photos:write"
+)
+
+// rolePermissions lists the permissions of each role. Regular users have none;
+// they can only act on their own account and photos.
+var rolePermissions = map[Role][]Permission{
+	RoleUser:      nil,
+	RoleModerator: {PermUsersRead, PermUsersWrite, PermPhotosRead, PermPhotosWrite},
+	RoleAdmin:     {PermUsersRead, PermUsersWrite, PermUsersRoles, PermPhotosRead, PermPhotosWrite},
+}
+
+// Valid reports whether r is a known role
+func (r Role) Valid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// Can reports whether r grants every permission in perms
+func (r Role) Can(perms ...Permission) bool {
+	for _, perm := range perms {
+		granted := false
+		for _, p := range rolePermissions[r] {
+			if p == perm {
+				granted = true
+				break
+			}
+		}
+		if !granted {
+			return false
+		}
+	}
+	return true
+}
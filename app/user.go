@@ -1,14 +1,53 @@
 package app
 
-import "time"
+import (
+	"backend-api/redact"
+	"time"
+)
 
-// User represents a user of the application
+// User represents a user of the application. Password holds the bcrypt hash and
+// is never serialized; handlers respond with a view of the user instead.
 type User struct {
-	ID        uint      `json:"id"`
-	Username  string    `json:"username" binding:"required"`
-	Email     string    `json:"email" binding:"required,email"`
-	Password  string    `json:"password" binding:"required,min=6"`
-	Photos    []Photo   `json:"photos"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID              uint          `json:"id"`
+	Username        string        `json:"username"`
+	Email           string        `json:"email"`
+	Password        redact.Secret `json:"-"`
+	Photos          []Photo       `json:"photos"`
+	EmailVerifiedAt *time.Time    `json:"email_verified_at"`
+	Role            Role          `json:"role"`
+	SuspendedAt     *time.Time    `json:"suspended_at"`
+	DeletedAt       *time.Time    `json:"deleted_at"`
+	CreatedAt       time.Time     `json:"created_at"`
+	UpdatedAt       time.Time     `json:"updated_at"`
+}
+
+// UserStatus tells whether a user may sign in
+type UserStatus string
+
+const (
+	UserActive    UserStatus = "active"
+	UserSuspended UserStatus = "suspended"
+	// UserDeleted users can be restored until they are purged
+	UserDeleted UserStatus = "deleted"
+)
+
+// Status returns the status of u. Deletion takes precedence over suspension.
+func (u User) Status() UserStatus {
+	switch {
+	case u.DeletedAt != nil:
+		return UserDeleted
+	case u.SuspendedAt != nil:
+		return UserSuspended
+	default:
+		return UserActive
+	}
+}
+
+// UserUpdate holds the fields of a partial user update; nil fields are left unchanged.
+// Password must already be hashed.
+type UserUpdate struct {
+	Username  *string
+	Email     *string
+	Password  *redact.Secret
+	UpdatedAt time.Time
 }
@@ -0,0 +1,203 @@
+package config
+
+import (
+	"bytes"
+	"encoding"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultFile is the YAML file read when CONFIG_FILE is not set
+const defaultFile = "config.yaml"
+
+// Load reads the configuration from the defaults, the YAML file, the .env file
+// and the environment, and validates it. Missing files are skipped, except a
+// YAML file named explicitly by CONFIG_FILE.
+func Load() (Config, error) {
+	cfg, err := read()
+	if err != nil {
+		return Config{}, err
+	}
+	envErr := readEnv(reflect.ValueOf(&cfg).Elem())
+
+	if cfg.Server.PublicURL == "" {
+		cfg.Server.PublicURL = "http://localhost:" + cfg.Server.Port
+	}
+	if err := errors.Join(envErr, cfg.Validate()); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// LoadDatabase reads the configuration like Load but only reads and validates
+// the database settings, so tools such as the migrate subcommand run without
+// the settings of the other subsystems.
+func LoadDatabase() (Database, error) {
+	cfg, err := read()
+	if err != nil {
+		return Database{}, err
+	}
+	db := cfg.Database
+	if err := errors.Join(readEnv(reflect.ValueOf(&db).Elem()), db.Validate()); err != nil {
+		return Database{}, err
+	}
+	return db, nil
+}
+
+// read loads the .env file and returns the defaults overridden by the YAML file
+func read() (Config, error) {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return Config{}, fmt.Errorf("reading .env: %w", err)
+	}
+
+	cfg := Default()
+
+	file, explicit := os.LookupEnv("CONFIG_FILE")
+	if !explicit || file == "" {
+		file = defaultFile
+	}
+	if err := readFile(&cfg, file); err != nil {
+		if explicit || !errors.Is(err, fs.ErrNotExist) {
+			return Config{}, err
+		}
+	}
+	return cfg, nil
+}
+
+// readFile overrides cfg with the settings in the YAML file. Unknown keys are
+// rejected so that typos do not go unnoticed.
+func readFile(cfg *Config, file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && err != io.EOF {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	return nil
+}
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// readEnv overrides the fields of the struct v that have an env tag with the
+// environment variable of that name. Empty variables are ignored.
+func readEnv(v reflect.Value) error {
+	var errs []error
+	for i := 0; i < v.NumField(); i++ {
+		field, value := v.Type().Field(i), v.Field(i)
+		key := field.Tag.Get("env")
+		if key == "" {
+			if value.Kind() == reflect.Struct {
+				errs = append(errs, readEnv(value))
+			}
+			continue
+		}
+
+		if raw := os.Getenv(key); raw != "" {
+			if err := setValue(value, raw); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", key, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// setValue parses raw into value according to its type
+func setValue(value reflect.Value, raw string) error {
+	if value.Addr().Type().Implements(textUnmarshalerType) {
+		return value.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw))
+	}
+	if value.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", raw)
+		}
+		value.SetInt(int64(d))
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		value.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		value.SetInt(n)
+	default:
+		return fmt.Errorf("unsupported type %s", value.Type())
+	}
+	return nil
+}
+
+// RateLimit allows Requests requests per Window. The zero value disables the limit.
+type RateLimit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// Enabled reports whether the limit applies
+func (l RateLimit) Enabled() bool {
+	return l.Requests > 0
+}
+
+func (l RateLimit) String() string {
+	if !l.Enabled() {
+		return "off"
+	}
+	return strconv.Itoa(l.Requests) + "/" + l.Window.String()
+}
+
+// UnmarshalText parses "<requests>/<window>", e.g. "60/1m", or "off"
+func (l *RateLimit) UnmarshalText(text []byte) error {
+	value := string(text)
+	if value == "off" {
+		*l = RateLimit{}
+		return nil
+	}
+
+	count, window, ok := strings.Cut(value, "/")
+	n, err := strconv.Atoi(count)
+	d, err2 := time.ParseDuration(window)
+	if !ok || err != nil || err2 != nil || n < 1 || d <= 0 {
+		return fmt.Errorf("invalid rate limit %q, want <requests>/<window> or off", value)
+	}
+	*l = RateLimit{Requests: n, Window: d}
+	return nil
+}
+
+func (l RateLimit) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// UnmarshalYAML accepts the same strings as UnmarshalText
+func (l *RateLimit) UnmarshalYAML(node *yaml.Node) error {
+	var value string
+	if err := node.Decode(&value); err != nil {
+		return err
+	}
+	return l.UnmarshalText([]byte(value))
+}
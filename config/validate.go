@@ -0,0 +1,144 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// problems collects the invalid settings found by a Validate method
+type problems []error
+
+// check records the problem described by format for the setting key unless ok
+func (p *problems) check(ok bool, key, format string, args ...interface{}) {
+	if !ok {
+		*p = append(*p, fmt.Errorf("%s: "+format, append([]interface{}{key}, args...)...))
+	}
+}
+
+// positive records a problem unless d is a positive duration
+func (p *problems) positive(key string, d time.Duration) {
+	p.check(d > 0, key, "must be a positive duration, got %s", d)
+}
+
+// Validate reports every invalid setting of c, named by its environment variable
+func (c Config) Validate() error {
+	var p problems
+	check, positive := p.check, p.positive
+
+	port, err := strconv.Atoi(c.Server.Port)
+	check(err == nil && port > 0 && port < 65536, "PORT", "must be a port number, got %q", c.Server.Port)
+	if u, err := url.Parse(c.Server.PublicURL); err != nil || u.Scheme == "" || u.Host == "" {
+		check(false, "APP_URL", "must be an absolute URL, got %q", c.Server.PublicURL)
+	}
+	check(c.Server.MaxUploadSize > 0, "MAX_UPLOAD_SIZE", "must be a positive number of bytes")
+	positive("HTTP_READ_HEADER_TIMEOUT", c.Server.ReadHeaderTimeout)
+	positive("HTTP_READ_TIMEOUT", c.Server.ReadTimeout)
+	positive("HTTP_WRITE_TIMEOUT", c.Server.WriteTimeout)
+	positive("HTTP_IDLE_TIMEOUT", c.Server.IdleTimeout)
+	positive("SHUTDOWN_TIMEOUT", c.Server.ShutdownTimeout)
+
+	if err := c.Database.Validate(); err != nil {
+		p = append(p, err)
+	}
+
+	switch c.JWT.SigningAlg {
+	case "HS256":
+		check(c.JWT.Secret != "", "JWT_SECRET", "must be set for HS256")
+	case "RS256", "EdDSA":
+		check(c.JWT.PrivateKeyFile != "", "JWT_PRIVATE_KEY_FILE", "must be set for %s", c.JWT.SigningAlg)
+	default:
+		check(false, "JWT_SIGNING_ALG", "must be HS256, RS256 or EdDSA, got %q", c.JWT.SigningAlg)
+	}
+	positive("ACCESS_TOKEN_TTL", c.JWT.AccessTokenTTL)
+	positive("REFRESH_TOKEN_TTL", c.JWT.RefreshTokenTTL)
+
+	positive("EMAIL_VERIFICATION_TTL", c.Accounts.EmailVerificationTTL)
+	positive("PASSWORD_RESET_TTL", c.Accounts.PasswordResetTTL)
+	positive("ACCOUNT_RESTORE_WINDOW", c.Accounts.RestoreWindow)
+	if c.Accounts.PasswordResetURL != "" {
+		u, err := url.Parse(c.Accounts.PasswordResetURL)
+		check(err == nil && u.Scheme != "" && u.Host != "", "PASSWORD_RESET_URL", "must be an absolute URL, got %q", c.Accounts.PasswordResetURL)
+	}
+
+	switch c.Storage.Driver {
+	case "local":
+		check(c.Storage.LocalDir != "", "STORAGE_LOCAL_DIR", "must be set for the local driver")
+		// The files are served at the path of the public URL, so it needs one
+		if c.Storage.PublicURL != "" {
+			u, err := url.Parse(c.Storage.PublicURL)
+			check(err == nil && strings.Trim(u.Path, "/") != "" && (u.Host != "" || strings.HasPrefix(u.Path, "/")),
+				"STORAGE_PUBLIC_URL", "must be a path such as /uploads or an absolute URL with a path, got %q", c.Storage.PublicURL)
+		}
+	case "s3":
+		check(c.Storage.S3.Endpoint != "", "S3_ENDPOINT", "must be set for the s3 driver")
+		check(c.Storage.S3.Bucket != "", "S3_BUCKET", "must be set for the s3 driver")
+	default:
+		check(false, "STORAGE_DRIVER", "must be local or s3, got %q", c.Storage.Driver)
+	}
+
+	switch c.Mail.Driver {
+	case "":
+		check(false, "MAIL_DRIVER", "must be set to smtp, or to log or file for development")
+	case "log":
+	case "file":
+		check(c.Mail.Dir != "", "MAIL_DIR", "must be set for the file driver")
+	case "smtp":
+		check(c.Mail.SMTP.Host != "", "SMTP_HOST", "must be set for the smtp driver")
+		check(c.Mail.SMTP.Port > 0 && c.Mail.SMTP.Port < 65536, "SMTP_PORT", "must be a port number, got %d", c.Mail.SMTP.Port)
+	default:
+		check(false, "MAIL_DRIVER", "must be log, file or smtp, got %q", c.Mail.Driver)
+	}
+	check(c.Mail.From != "", "MAIL_FROM", "must be set")
+
+	switch c.LoginGuard.Store {
+	case "memory":
+	case "redis":
+		check(c.LoginGuard.RedisURL != "", "REDIS_URL", "must be set for LOGIN_GUARD_STORE=redis")
+	default:
+		check(false, "LOGIN_GUARD_STORE", "must be memory or redis, got %q", c.LoginGuard.Store)
+	}
+
+	return errors.Join(p...)
+}
+
+// Validate reports every invalid database setting, named by its environment
+// variable. It is all the migrate subcommand checks.
+func (d Database) Validate() error {
+	var p problems
+	check := p.check
+
+	switch d.Driver {
+	case "mysql", "postgres":
+		check(d.Host != "", "DB_HOST", "must be set for %s", d.Driver)
+		check(d.User != "", "DB_USER", "must be set for %s", d.Driver)
+		check(d.Name != "", "DB_NAME", "must be set for %s", d.Driver)
+	case "sqlite":
+		check(d.Name != "", "DB_NAME", "must be the path of the database file")
+	default:
+		check(false, "DB_DRIVER", "must be mysql, postgres or sqlite, got %q", d.Driver)
+	}
+
+	switch d.TLS {
+	case "", "true", "false", "skip-verify", "preferred":
+	default:
+		check(false, "DB_TLS", "must be true, false, skip-verify or preferred, got %q", d.TLS)
+	}
+	if d.TLSCAFile != "" {
+		_, err := os.Stat(d.TLSCAFile)
+		check(err == nil, "DB_TLS_CA_FILE", "%v", err)
+	}
+	_, err := url.ParseQuery(d.Params)
+	check(err == nil, "DB_PARAMS", "must be in query string form, e.g. \"charset=utf8mb4\": %v", err)
+	check(d.MaxOpenConns >= 0, "DB_MAX_OPEN_CONNS", "must not be negative")
+	check(d.MaxIdleConns >= 0, "DB_MAX_IDLE_CONNS", "must not be negative")
+	check(d.ConnMaxLifetime >= 0, "DB_CONN_MAX_LIFETIME", "must not be negative")
+	check(d.ConnMaxIdleTime >= 0, "DB_CONN_MAX_IDLE_TIME", "must not be negative")
+	check(d.ConnectTimeout >= 0, "DB_CONNECT_TIMEOUT", "must not be negative")
+
+	return errors.Join(p...)
+}
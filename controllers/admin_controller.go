@@ -0,0 +1,263 @@
+package controllers
+
+import (
+	"backend-api/apierror"
+	"backend-api/app"
+	"backend-api/helpers"
+	"backend-api/middlewares"
+	"backend-api/models"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// AdminListUsers returns every user, newest first.
+// Supports the optional "limit" and "offset" query parameters.
+func (s *Server) AdminListUsers(w http.ResponseWriter, r *http.Request) {
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		apierror.Write(w, r, apierror.ErrInvalidRequest.WithDetail(err.Error()))
+		return
+	}
+
+	users, err := s.Users.ListUsers(limit, offset)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal("Error fetching users", err))
+		return
+	}
+
+	total, err := s.Users.CountUsers()
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal("Error fetching users", err))
+		return
+	}
+
+	views := make([]adminUserView, len(users))
+	for i, user := range users {
+		views[i] = newAdminUserView(user)
+	}
+	helpers.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"users":  views,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// AdminSuspendUser suspends a user and logs out all of their sessions.
+// Suspended users cannot log in or refresh tokens until they are unsuspended.
+func (s *Server) AdminSuspendUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.findModeratedUser(w, r)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	if err := s.Users.SetUserSuspended(user.ID, &now); err != nil {
+		apierror.Write(w, r, apierror.Internal("Error suspending user", err))
+		return
+	}
+	s.Accounts.Forget(user.ID)
+	if err := s.Revocations.RevokeAllForUser(user.ID); err != nil {
+		apierror.Write(w, r, apierror.Internal("Error suspending user", err))
+		return
+	}
+
+	s.respondAdminUser(w, r, user.ID)
+}
+
+// AdminUnsuspendUser lifts the suspension of a user
+func (s *Server) AdminUnsuspendUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.findModeratedUser(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.Users.SetUserSuspended(user.ID, nil); err != nil {
+		apierror.Write(w, r, apierror.Internal("Error unsuspending user", err))
+		return
+	}
+	s.Accounts.Forget(user.ID)
+
+	s.respondAdminUser(w, r, user.ID)
+}
+
+// AdminDeleteUser deletes any user and revokes their tokens. Like a deletion by
+// the user, it can be undone until the restore window has passed.
+func (s *Server) AdminDeleteUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.findModeratedUser(w, r)
+	if !ok {
+		return
+	}
+
+	if _, err := s.deleteAccount(user.ID); err != nil {
+		apierror.Write(w, r, apierror.Internal("Error deleting user", err))
+		return
+	}
+
+	s.respondAdminUser(w, r, user.ID)
+}
+
+// AdminRestoreUser undoes the deletion of a user that has not been purged yet
+func (s *Server) AdminRestoreUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.findModeratedUser(w, r)
+	if !ok {
+		return
+	}
+	if user.DeletedAt == nil {
+		apierror.Write(w, r, apierror.ErrAccountNotDeleted)
+		return
+	}
+
+	if err := s.Users.RestoreUser(user.ID); err != nil {
+		apierror.Write(w, r, apierror.Internal("Error restoring user", err))
+		return
+	}
+	s.Accounts.Forget(user.ID)
+
+	s.respondAdminUser(w, r, user.ID)
+}
+
+// AdminSetUserRole changes the role of a user. Existing sessions of the user are
+// logged out, since access tokens carry the role they were issued with.
+func (s *Server) AdminSetUserRole(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.findModeratedUser(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Role app.Role `json:"role" binding:"required,oneof=user moderator admin"`
+	}
+	if !decodeRequest(w, r, &input) {
+		return
+	}
+
+	if input.Role != user.Role {
+		if err := s.Users.SetUserRole(user.ID, input.Role); err != nil {
+			apierror.Write(w, r, apierror.Internal("Error changing role", err))
+			return
+		}
+		if err := s.Revocations.RevokeAllForUser(user.ID); err != nil {
+			apierror.Write(w, r, apierror.Internal("Error changing role", err))
+			return
+		}
+	}
+
+	s.respondAdminUser(w, r, user.ID)
+}
+
+// AdminListPhotos returns the photos of every user, newest first.
+// Supports the optional "user_id", "limit" and "offset" query parameters.
+func (s *Server) AdminListPhotos(w http.ResponseWriter, r *http.Request) {
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		apierror.Write(w, r, apierror.ErrInvalidRequest.WithDetail(err.Error()))
+		return
+	}
+
+	var photos []app.Photo
+	var total int
+	if value := r.URL.Query().Get("user_id"); value != "" {
+		var userID uint64
+		userID, err = strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			apierror.Write(w, r, apierror.ErrInvalidRequest.WithDetail("user_id must be a user ID"))
+			return
+		}
+		photos, err = s.Photos.GetUserPhotos(uint(userID), limit, offset)
+		if err == nil {
+			total, err = s.Photos.CountUserPhotos(uint(userID))
+		}
+	} else {
+		photos, err = s.Photos.ListPhotos(limit, offset)
+		if err == nil {
+			total, err = s.Photos.CountPhotos()
+		}
+	}
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal("Error fetching photos", err))
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"photos": photos,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// AdminDeletePhoto deletes a photo of any user, together with its stored file
+func (s *Server) AdminDeletePhoto(w http.ResponseWriter, r *http.Request) {
+	photoID, ok := parsePhotoID(w, r)
+	if !ok {
+		return
+	}
+
+	photo, err := s.Photos.FindPhoto(photoID)
+	if errors.Is(err, models.ErrNotFound) {
+		apierror.Write(w, r, apierror.ErrPhotoNotFound)
+		return
+	}
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal("Error fetching photo", err))
+		return
+	}
+
+	if err := s.Photos.DeletePhoto(photo.ID, photo.UserID); err != nil {
+		apierror.Write(w, r, apierror.Internal("Error deleting photo", err))
+		return
+	}
+	s.removeStoredPhoto(r, photo.UserID, photo.PhotoURL)
+
+	helpers.RespondWithJSON(w, http.StatusOK, map[string]string{"result": "success"})
+}
+
+// findModeratedUser loads the user in the userId path parameter and checks that
+// the caller may moderate them. Staff cannot moderate themselves, and only
+// admins can moderate other staff.
+func (s *Server) findModeratedUser(w http.ResponseWriter, r *http.Request) (app.User, bool) {
+	userID, ok := parseUserID(w, r)
+	if !ok {
+		return app.User{}, false
+	}
+	callerID, _ := middlewares.GetUserContextKey(r)
+	caller, ok := middlewares.GetTokenInfo(r)
+	if !ok {
+		apierror.Write(w, r, apierror.ErrAuthTokenInvalid)
+		return app.User{}, false
+	}
+
+	if userID == callerID {
+		apierror.Write(w, r, apierror.ErrForbidden.WithDetail("You cannot moderate your own account"))
+		return app.User{}, false
+	}
+
+	user, err := s.Users.GetUserByID(userID)
+	if errors.Is(err, models.ErrNotFound) {
+		apierror.Write(w, r, apierror.ErrUserNotFound)
+		return app.User{}, false
+	}
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal("Error fetching user", err))
+		return app.User{}, false
+	}
+
+	if user.Role != app.RoleUser && caller.Role != app.RoleAdmin {
+		apierror.Write(w, r, apierror.ErrForbidden.WithDetail("Only admins can moderate staff accounts"))
+		return app.User{}, false
+	}
+	return user, true
+}
+
+// respondAdminUser responds with the stored state of a moderated user
+func (s *Server) respondAdminUser(w http.ResponseWriter, r *http.Request, userID uint) {
+	user, err := s.Users.GetUserByID(userID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal("Error fetching user", err))
+		return
+	}
+	helpers.RespondWithJSON(w, http.StatusOK, newAdminUserView(user))
+}
@@ -0,0 +1,61 @@
+package controllers_test
+
+import (
+	"backend-api/app"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// signUpAs registers a user with role and returns its ID and an access token
+// carrying the role
+func (ts *testServer) signUpAs(username, email string, role app.Role) (uint, string) {
+	ts.t.Helper()
+
+	userID, _ := ts.signUp(username, email)
+	if err := ts.repos.SetUserRole(userID, role); err != nil {
+		ts.t.Fatal(err)
+	}
+	return userID, ts.login(email).Token
+}
+
+func TestAdminRoutesRequirePermissions(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t)
+	bobID, _ := ts.signUp("bob", "bob@example.com")
+	_, userToken := ts.signUp("alice", "alice@example.com")
+
+	expectProblem(t, ts.do("GET", "/admin/users", userToken, nil), http.StatusForbidden, "FORBIDDEN")
+	expectProblem(t, ts.do("POST", fmt.Sprintf("/admin/users/%d/suspend", bobID), userToken, nil), http.StatusForbidden, "FORBIDDEN")
+	expectProblem(t, ts.do("GET", "/admin/users", "", nil), http.StatusUnauthorized, "AUTH_TOKEN_MISSING")
+}
+
+func TestModeratorCannotModerateStaffOrChangeRoles(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t)
+	userID, _ := ts.signUp("bob", "bob@example.com")
+	adminID, _ := ts.signUpAs("carol", "carol@example.com", app.RoleAdmin)
+	_, modToken := ts.signUpAs("mod", "mod@example.com", app.RoleModerator)
+
+	expectStatus(t, ts.do("GET", "/admin/users", modToken, nil), http.StatusOK)
+	expectStatus(t, ts.do("POST", fmt.Sprintf("/admin/users/%d/suspend", userID), modToken, nil), http.StatusOK)
+
+	expectProblem(t, ts.do("POST", fmt.Sprintf("/admin/users/%d/suspend", adminID), modToken, nil), http.StatusForbidden, "FORBIDDEN")
+	expectProblem(t, ts.do("DELETE", fmt.Sprintf("/admin/users/%d", adminID), modToken, nil), http.StatusForbidden, "FORBIDDEN")
+	expectProblem(t, ts.do("PUT", fmt.Sprintf("/admin/users/%d/role", userID), modToken, map[string]string{"role": "admin"}), http.StatusForbidden, "FORBIDDEN")
+
+	admin, err := ts.repos.GetUserByID(adminID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if admin.SuspendedAt != nil || admin.DeletedAt != nil {
+		t.Errorf("admin = %+v, want it untouched by the moderator", admin)
+	}
+	user, err := ts.repos.GetUserByID(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Role != app.RoleUser {
+		t.Errorf("role = %q, want %q", user.Role, app.RoleUser)
+	}
+}
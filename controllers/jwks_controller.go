@@ -0,0 +1,13 @@
+package controllers
+
+import (
+	"backend-api/helpers"
+	"net/http"
+)
+
+// GetJWKS publishes the public keys that verify access tokens as a JSON Web Key Set,
+// so other services can verify tokens without the signing key.
+func GetJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	helpers.RespondWithJSON(w, http.StatusOK, helpers.JWKS())
+}
@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"backend-api/apierror"
+	"backend-api/app"
+	"backend-api/helpers"
+	"backend-api/mailer"
+	"backend-api/models"
+	"backend-api/redact"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ForgotPassword emails a single-use password reset token. The lookup and the
+// email happen in the background so the response, and its timing, do not reveal
+// whether the email is registered.
+func (s *Server) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+	if !decodeRequest(w, r, &input) {
+		return
+	}
+
+	s.goBackground(func() { s.sendPasswordReset(input.Email) })
+
+	helpers.RespondWithJSON(w, http.StatusAccepted, map[string]string{"result": "If the account exists, a password reset email has been sent"})
+}
+
+// ResetPassword consumes a password reset token, sets the new password and
+// logs out every session of the user
+func (s *Server) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Token    redact.Secret `json:"token" binding:"required"`
+		Password redact.Secret `json:"password" binding:"required,min=6"`
+	}
+	if !decodeRequest(w, r, &input) {
+		return
+	}
+
+	hashedPassword, err := helpers.HashPassword(input.Password)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal("Error hashing password", err))
+		return
+	}
+
+	userID, err := s.Tokens.ResetPassword(helpers.HashToken(input.Token.Reveal()), hashedPassword, time.Now())
+	if errors.Is(err, models.ErrPasswordResetTokenInvalid) {
+		apierror.Write(w, r, apierror.ErrResetTokenInvalid)
+		return
+	}
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal("Error resetting password", err))
+		return
+	}
+
+	if err := s.Revocations.RevokeAllForUser(userID); err != nil {
+		log.Printf("Error revoking sessions for user ID %d: %v", userID, err)
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, map[string]string{"result": "success"})
+}
+
+// sendPasswordReset creates a reset token for the account with email, if any, and emails it
+func (s *Server) sendPasswordReset(email string) {
+	user, err := s.Users.GetUserByEmail(email)
+	// Deleted accounts are restored with their old password, not reset
+	if errors.Is(err, models.ErrNotFound) || err == nil && user.DeletedAt != nil {
+		return
+	}
+	if err != nil {
+		log.Printf("Error retrieving user by email: %v", err)
+		return
+	}
+
+	plain, hash, err := helpers.GenerateOpaqueToken()
+	if err != nil {
+		log.Printf("Error generating password reset token: %v", err)
+		return
+	}
+
+	now := time.Now()
+	token := app.PasswordResetToken{UserID: user.ID, TokenHash: hash, ExpiresAt: now.Add(s.Config.Accounts.PasswordResetTTL), CreatedAt: now}
+	if err := s.Tokens.CreatePasswordResetToken(&token); err != nil {
+		log.Printf("Error saving password reset token for user ID %d: %v", user.ID, err)
+		return
+	}
+
+	instructions := "Use this token to reset your password:\n\n" + plain
+	if s.Config.Accounts.PasswordResetURL != "" {
+		instructions = "Reset your password by opening this link:\n\n" + s.Config.Accounts.PasswordResetURL + "?token=" + url.QueryEscape(plain)
+	}
+	s.sendMail(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("%s\n\n%s\n\nThe token expires in %s and can be used once. If you did not ask to reset your password, ignore this email.\n",
+			greeting(user), instructions, s.Config.Accounts.PasswordResetTTL),
+	})
+}
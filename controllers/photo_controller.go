@@ -1,11 +1,16 @@
 package controllers
 
 import (
+	"backend-api/apierror"
 	"backend-api/app"
 	"backend-api/helpers"
 	"backend-api/middlewares"
 	"backend-api/models"
-	"encoding/json"
+	"backend-api/storage"
+	"backend-api/validation"
+	"bufio"
+	"errors"
+	"io"
 	"log"
 	"net/http"
 	"strconv"
@@ -15,51 +20,55 @@ import (
 )
 
 // SetProfilePhoto sets a photo as the user's profile photo.
-func SetProfilePhoto(w http.ResponseWriter, r *http.Request) {
+func (s *Server) SetProfilePhoto(w http.ResponseWriter, r *http.Request) {
 	var photo app.Photo
-	if err := json.NewDecoder(r.Body).Decode(&photo); err != nil {
-		helpers.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+	if !decodeRequest(w, r, &photo) {
 		return
 	}
 
 	userID, ok := middlewares.GetUserContextKey(r)
 	if !ok {
-		helpers.RespondWithError(w, http.StatusUnauthorized, "Invalid user ID in token")
+		apierror.Write(w, r, apierror.ErrAuthTokenInvalid)
 		return
 	}
 
-	log.Printf("User ID from context: %d", userID)
+	if !s.checkPhotoURL(w, r, userID, photo.PhotoURL) {
+		return
+	}
 
-	// Retrieve the existing profile photo of the user
-	existingProfilePhotos, err := models.GetUserProfilePhotos(userID)
-	if err != nil {
-		helpers.RespondWithError(w, http.StatusInternalServerError, "Error fetching profile photos")
+	// Add the new photo and make it the profile photo; the previous profile
+	// photo stays in the gallery
+	photo.UserID = userID
+	photo.CreatedAt = time.Now()
+	photo.UpdatedAt = time.Now()
+	if err := s.Photos.CreateProfilePhoto(&photo); err != nil {
+		respondProfilePhotoError(w, r, err)
 		return
 	}
 
-	if len(existingProfilePhotos) > 0 {
-		// Update the existing profile photo without changing created_at
-		existingPhoto := existingProfilePhotos[0]
-		existingPhoto.PhotoURL = photo.PhotoURL
-		existingPhoto.IsProfile = true
-		existingPhoto.UpdatedAt = time.Now()
+	helpers.RespondWithJSON(w, http.StatusOK, photo)
+}
 
-		if err := models.UpdatePhoto(&existingPhoto); err != nil {
-			helpers.RespondWithError(w, http.StatusInternalServerError, "Error updating profile photo")
-			return
-		}
+// PromoteProfilePhoto makes an existing gallery photo the user's profile photo.
+func (s *Server) PromoteProfilePhoto(w http.ResponseWriter, r *http.Request) {
+	photoID, ok := parsePhotoID(w, r)
+	if !ok {
+		return
+	}
 
-		helpers.RespondWithJSON(w, http.StatusOK, existingPhoto)
+	userID, ok := middlewares.GetUserContextKey(r)
+	if !ok {
+		apierror.Write(w, r, apierror.ErrAuthTokenInvalid)
 		return
 	}
 
-	// Set the new photo as the profile photo
-	photo.IsProfile = true
-	photo.UserID = userID
-	photo.CreatedAt = time.Now()
-	photo.UpdatedAt = time.Now()
-	if err := models.CreatePhoto(&photo); err != nil {
-		helpers.RespondWithError(w, http.StatusInternalServerError, "Error setting profile photo")
+	photo, err := s.Photos.SetProfilePhoto(photoID, userID)
+	if errors.Is(err, models.ErrNotFound) {
+		apierror.Write(w, r, apierror.ErrPhotoNotFound)
+		return
+	}
+	if err != nil {
+		respondProfilePhotoError(w, r, err)
 		return
 	}
 
@@ -67,56 +76,413 @@ func SetProfilePhoto(w http.ResponseWriter, r *http.Request) {
 }
 
 // GetProfilePhoto retrieves the profile photo URL of the user.
-func GetProfilePhoto(w http.ResponseWriter, r *http.Request) {
+func (s *Server) GetProfilePhoto(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middlewares.GetUserContextKey(r)
 	if !ok {
-		helpers.RespondWithError(w, http.StatusUnauthorized, "Invalid user ID in token")
+		apierror.Write(w, r, apierror.ErrAuthTokenInvalid)
 		return
 	}
 
-	photoURL, err := models.GetUserProfilePhoto(userID)
+	photoURL, err := s.Photos.GetUserProfilePhoto(userID)
 	if err != nil {
-		helpers.RespondWithError(w, http.StatusInternalServerError, "Error fetching profile photo")
+		apierror.Write(w, r, apierror.Internal("Error fetching profile photo", err))
 		return
 	}
 
 	helpers.RespondWithJSON(w, http.StatusOK, map[string]string{"photo_url": photoURL})
 }
 
+// defaultPhotoPageSize and maxPhotoPageSize bound the "limit" query parameter of ListPhotos
+const (
+	defaultPhotoPageSize = 20
+	maxPhotoPageSize     = 100
+)
+
+// ListPhotos returns the caller's photos, newest first.
+// Supports the optional "limit" and "offset" query parameters.
+func (s *Server) ListPhotos(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middlewares.GetUserContextKey(r)
+	if !ok {
+		apierror.Write(w, r, apierror.ErrAuthTokenInvalid)
+		return
+	}
+
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		apierror.Write(w, r, apierror.ErrInvalidRequest.WithDetail(err.Error()))
+		return
+	}
+
+	photos, err := s.Photos.GetUserPhotos(userID, limit, offset)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal("Error fetching photos", err))
+		return
+	}
+
+	total, err := s.Photos.CountUserPhotos(userID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal("Error fetching photos", err))
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"photos": photos,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// GetPhoto returns a single photo owned by the caller.
+func (s *Server) GetPhoto(w http.ResponseWriter, r *http.Request) {
+	photoID, ok := parsePhotoID(w, r)
+	if !ok {
+		return
+	}
+
+	userID, ok := middlewares.GetUserContextKey(r)
+	if !ok {
+		apierror.Write(w, r, apierror.ErrAuthTokenInvalid)
+		return
+	}
+
+	photo, ok := s.findPhoto(w, r, photoID, userID)
+	if !ok {
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, photo)
+}
+
+// CreatePhoto adds a photo to the caller's gallery from a photo URL.
+// Photos created here are never the profile photo.
+func (s *Server) CreatePhoto(w http.ResponseWriter, r *http.Request) {
+	var photo app.Photo
+	if !decodeRequest(w, r, &photo) {
+		return
+	}
+
+	userID, ok := middlewares.GetUserContextKey(r)
+	if !ok {
+		apierror.Write(w, r, apierror.ErrAuthTokenInvalid)
+		return
+	}
+
+	if !s.checkPhotoURL(w, r, userID, photo.PhotoURL) {
+		return
+	}
+
+	photo.ID = 0
+	photo.IsProfile = false
+	photo.UserID = userID
+	photo.CreatedAt = time.Now()
+	photo.UpdatedAt = time.Now()
+	if err := s.Photos.CreatePhoto(&photo); err != nil {
+		apierror.Write(w, r, apierror.Internal("Error saving photo", err))
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusCreated, photo)
+}
+
+// UpdatePhoto edits the metadata of a photo owned by the caller.
+// Only photo_url can be changed; use the profile endpoints to change is_profile.
+func (s *Server) UpdatePhoto(w http.ResponseWriter, r *http.Request) {
+	photoID, ok := parsePhotoID(w, r)
+	if !ok {
+		return
+	}
+
+	var input app.Photo
+	if !decodeRequest(w, r, &input) {
+		return
+	}
+
+	userID, ok := middlewares.GetUserContextKey(r)
+	if !ok {
+		apierror.Write(w, r, apierror.ErrAuthTokenInvalid)
+		return
+	}
+
+	if !s.checkPhotoURL(w, r, userID, input.PhotoURL) {
+		return
+	}
+
+	photo, ok := s.findPhoto(w, r, photoID, userID)
+	if !ok {
+		return
+	}
+
+	oldURL := photo.PhotoURL
+	photo.PhotoURL = input.PhotoURL
+	photo.UpdatedAt = time.Now()
+	if err := s.Photos.UpdatePhoto(photo); err != nil {
+		apierror.Write(w, r, apierror.Internal("Error updating photo", err))
+		return
+	}
+
+	if oldURL != photo.PhotoURL {
+		s.removeStoredPhoto(r, userID, oldURL)
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, photo)
+}
+
 // DeletePhoto menangani penghapusan foto.
-func DeletePhoto(w http.ResponseWriter, r *http.Request) {
+func (s *Server) DeletePhoto(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	photoID, err := strconv.ParseUint(params["photoId"], 10, 64)
 	if err != nil {
-		helpers.RespondWithError(w, http.StatusBadRequest, "Invalid photo ID")
+		apierror.Write(w, r, apierror.ErrPhotoIDInvalid)
 		return
 	}
 
 	userID, ok := middlewares.GetUserContextKey(r)
 	if !ok {
-		helpers.RespondWithError(w, http.StatusUnauthorized, "Invalid user ID in token")
+		apierror.Write(w, r, apierror.ErrAuthTokenInvalid)
 		return
 	}
 
 	// Mengambil informasi foto dari database
-	foundPhoto, err := models.GetPhotoByID(uint(photoID), userID)
+	foundPhoto, err := s.Photos.GetPhotoByID(uint(photoID), userID)
 	if err != nil {
 		// Jika foto tidak ditemukan atau pengguna tidak memiliki hak akses, kirim pesan kesalahan
-		helpers.RespondWithError(w, http.StatusNotFound, "Photo not found or user does not have access")
+		apierror.Write(w, r, apierror.ErrPhotoNotFound)
 		return
 	}
 
 	// Hapus foto hanya jika pengguna memiliki akses ke foto tersebut
 	if foundPhoto.UserID != userID {
-		helpers.RespondWithError(w, http.StatusForbidden, "You do not have permission to delete this photo")
+		apierror.Write(w, r, apierror.ErrForbidden.WithDetail("You do not have permission to delete this photo"))
 		return
 	}
 
 	// Hapus foto dengan ID tertentu untuk pengguna tertentu
-	if err := models.DeletePhoto(uint(photoID), userID); err != nil {
-		helpers.RespondWithError(w, http.StatusInternalServerError, "Error deleting photo")
+	if err := s.Photos.DeletePhoto(uint(photoID), userID); err != nil {
+		apierror.Write(w, r, apierror.Internal("Error deleting photo", err))
 		return
 	}
 
+	// Hapus juga berkas foto yang tersimpan di storage
+	s.removeStoredPhoto(r, userID, foundPhoto.PhotoURL)
+
 	helpers.RespondWithJSON(w, http.StatusOK, map[string]string{"result": "success"})
 }
+
+// allowedPhotoTypes maps the accepted image content types to file extensions
+var allowedPhotoTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// UploadPhoto handles multipart/form-data photo uploads.
+// The "photo" part is streamed into the configured storage backend and the
+// resulting URL is saved as a new photo. An "is_profile" field set to "true"
+// makes the uploaded photo the user's profile photo.
+func (s *Server) UploadPhoto(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middlewares.GetUserContextKey(r)
+	if !ok {
+		apierror.Write(w, r, apierror.ErrAuthTokenInvalid)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, s.Config.Server.MaxUploadSize)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		apierror.Write(w, r, apierror.ErrInvalidRequest.WithDetail("Request must be multipart/form-data"))
+		return
+	}
+
+	var photo app.Photo
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			s.removeStoredPhoto(r, userID, photo.PhotoURL)
+			respondUploadError(w, r, err)
+			return
+		}
+
+		switch part.FormName() {
+		case "photo":
+			if photo.PhotoURL != "" {
+				part.Close()
+				s.removeStoredPhoto(r, userID, photo.PhotoURL)
+				apierror.Write(w, r, apierror.ErrInvalidRequest.WithDetail("Only one photo can be uploaded per request"))
+				return
+			}
+			photo.PhotoURL, err = s.storePhoto(r, userID, part)
+			if err != nil {
+				part.Close()
+				respondUploadError(w, r, err)
+				return
+			}
+		case "is_profile":
+			value, _ := io.ReadAll(io.LimitReader(part, 16))
+			photo.IsProfile, _ = strconv.ParseBool(string(value))
+		}
+		part.Close()
+	}
+
+	if photo.PhotoURL == "" {
+		apierror.Write(w, r, apierror.ErrInvalidRequest.WithDetail("Missing photo file"))
+		return
+	}
+
+	photo.UserID = userID
+	photo.CreatedAt = time.Now()
+	photo.UpdatedAt = time.Now()
+	if photo.IsProfile {
+		err = s.Photos.CreateProfilePhoto(&photo)
+	} else {
+		err = s.Photos.CreatePhoto(&photo)
+	}
+	if err != nil {
+		s.removeStoredPhoto(r, userID, photo.PhotoURL)
+		respondProfilePhotoError(w, r, err)
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusCreated, photo)
+}
+
+// errUnsupportedPhotoType is returned by storePhoto for non-image uploads
+var errUnsupportedPhotoType = errors.New("unsupported photo type")
+
+// storePhoto sniffs the content type of part and streams it into storage
+func (s *Server) storePhoto(r *http.Request, userID uint, part io.Reader) (string, error) {
+	buffered := bufio.NewReader(part)
+	head, err := buffered.Peek(512)
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+
+	contentType := http.DetectContentType(head)
+	ext, ok := allowedPhotoTypes[contentType]
+	if !ok {
+		return "", errUnsupportedPhotoType
+	}
+
+	key, err := storage.NewKey(storage.PhotoKeyPrefix(userID), ext)
+	if err != nil {
+		return "", err
+	}
+
+	return s.Storage.Put(r.Context(), key, buffered, -1, contentType)
+}
+
+// checkPhotoURL accepts the URLs of files the caller uploaded to the storage
+// backend, which may be relative, and absolute http or https URLs outside it.
+// URLs of files another user uploaded are rejected.
+func (s *Server) checkPhotoURL(w http.ResponseWriter, r *http.Request, userID uint, photoURL string) bool {
+	var fieldErr validation.FieldError
+	key, stored := s.Storage.KeyFromURL(photoURL)
+	switch {
+	case stored && !storage.IsPhotoKeyOf(key, userID):
+		fieldErr = validation.FieldError{Field: "photo_url", Rule: "owner", Message: "photo_url must be a photo you uploaded"}
+	case !stored && !validation.IsURL(photoURL):
+		fieldErr = validation.FieldError{Field: "photo_url", Rule: "url", Message: "photo_url must be a valid http or https URL"}
+	default:
+		return true
+	}
+	apierror.Write(w, r, validation.Errors{fieldErr})
+	return false
+}
+
+// removeStoredPhoto deletes the stored file of a photo of ownerID whose row could
+// not be saved or has been deleted. Files uploaded by other users, files that
+// another photo row still references and URLs outside the storage backend are
+// left alone.
+func (s *Server) removeStoredPhoto(r *http.Request, ownerID uint, photoURL string) {
+	if photoURL == "" {
+		return
+	}
+	key, ok := s.Storage.KeyFromURL(photoURL)
+	if !ok || !storage.IsPhotoKeyOf(key, ownerID) {
+		return
+	}
+	refs, err := s.Photos.CountPhotosByURL(photoURL)
+	if err != nil {
+		log.Printf("Error checking references to stored photo %s: %v", key, err)
+		return
+	}
+	if refs > 0 {
+		return
+	}
+	if err := s.Storage.Delete(r.Context(), key); err != nil {
+		log.Printf("Error deleting stored photo %s: %v", key, err)
+	}
+}
+
+// respondProfilePhotoError reports a failure to save a (profile) photo
+func respondProfilePhotoError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, models.ErrProfilePhotoConflict) {
+		apierror.Write(w, r, err)
+		return
+	}
+	apierror.Write(w, r, apierror.Internal("Error saving photo", err))
+}
+
+func respondUploadError(w http.ResponseWriter, r *http.Request, err error) {
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		apierror.Write(w, r, apierror.ErrPhotoTooLarge)
+	case errors.Is(err, errUnsupportedPhotoType):
+		apierror.Write(w, r, apierror.ErrPhotoUnsupportedType)
+	default:
+		apierror.Write(w, r, apierror.Internal("Error uploading photo", err))
+	}
+}
+
+// parsePhotoID reads the photoId path parameter, responding with 400 when it is invalid
+func parsePhotoID(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	photoID, err := strconv.ParseUint(mux.Vars(r)["photoId"], 10, 64)
+	if err != nil {
+		apierror.Write(w, r, apierror.ErrPhotoIDInvalid)
+		return 0, false
+	}
+	return uint(photoID), true
+}
+
+// findPhoto loads a photo owned by userID, responding with 404 when it does not exist
+func (s *Server) findPhoto(w http.ResponseWriter, r *http.Request, photoID, userID uint) (*app.Photo, bool) {
+	photo, err := s.Photos.GetPhotoByID(photoID, userID)
+	if errors.Is(err, models.ErrNotFound) {
+		apierror.Write(w, r, apierror.ErrPhotoNotFound)
+		return nil, false
+	}
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal("Error fetching photo", err))
+		return nil, false
+	}
+	return photo, true
+}
+
+// parsePagination reads the "limit" and "offset" query parameters
+func parsePagination(r *http.Request) (int, int, error) {
+	limit, offset := defaultPhotoPageSize, 0
+	query := r.URL.Query()
+
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxPhotoPageSize {
+			return 0, 0, errors.New("limit must be between 1 and " + strconv.Itoa(maxPhotoPageSize))
+		}
+		limit = n
+	}
+
+	if value := query.Get("offset"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return 0, 0, errors.New("offset must be a non-negative integer")
+		}
+		offset = n
+	}
+
+	return limit, offset, nil
+}
@@ -0,0 +1,263 @@
+package controllers_test
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// photo is a photo as returned by the photo endpoints
+type photo struct {
+	ID        uint   `json:"id"`
+	PhotoURL  string `json:"photo_url"`
+	UserID    uint   `json:"user_id"`
+	IsProfile bool   `json:"is_profile"`
+}
+
+// pngHeader is enough of a PNG file for content type sniffing
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func (ts *testServer) createPhoto(token, photoURL string) photo {
+	ts.t.Helper()
+	rec := ts.do("POST", "/api/photos", token, map[string]string{"photo_url": photoURL})
+	expectStatus(ts.t, rec, http.StatusCreated)
+	var created photo
+	decode(ts.t, rec, &created)
+	return created
+}
+
+// upload stores a PNG through the upload endpoint and returns the new photo
+func (ts *testServer) upload(token string) photo {
+	ts.t.Helper()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("photo", "photo.png")
+	if err != nil {
+		ts.t.Fatal(err)
+	}
+	part.Write(pngHeader)
+	form.Close()
+
+	req := httptest.NewRequest("POST", "/api/photos/upload", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	rec := ts.send(req, token)
+	expectStatus(ts.t, rec, http.StatusCreated)
+	var uploaded photo
+	decode(ts.t, rec, &uploaded)
+	return uploaded
+}
+
+// storedFile returns the path of the file behind an uploaded photo URL
+func (ts *testServer) storedFile(photoURL string) string {
+	ts.t.Helper()
+	key, ok := ts.files.KeyFromURL(photoURL)
+	if !ok {
+		ts.t.Fatalf("%s is not a storage URL", photoURL)
+	}
+	return filepath.Join(ts.files.Dir, filepath.FromSlash(key))
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func TestPhotoCRUD(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t)
+	userID, token := ts.signUp("alice", "alice@example.com")
+
+	created := ts.createPhoto(token, "http://example.com/a.jpg")
+	if created.ID == 0 || created.UserID != userID || created.IsProfile {
+		t.Fatalf("created photo = %+v, want a gallery photo of user %d", created, userID)
+	}
+	ts.createPhoto(token, "http://example.com/b.jpg")
+
+	rec := ts.do("GET", "/api/photos?limit=1", token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	var page struct {
+		Photos []photo `json:"photos"`
+		Total  int     `json:"total"`
+	}
+	decode(t, rec, &page)
+	if page.Total != 2 || len(page.Photos) != 1 || page.Photos[0].PhotoURL != "http://example.com/b.jpg" {
+		t.Fatalf("page = %+v, want the newest of 2 photos", page)
+	}
+
+	path := fmt.Sprintf("/api/photos/%d", created.ID)
+	rec = ts.do("GET", path, token, nil)
+	expectStatus(t, rec, http.StatusOK)
+
+	rec = ts.do("PUT", path, token, map[string]string{"photo_url": "http://example.com/a-edited.jpg"})
+	expectStatus(t, rec, http.StatusOK)
+	var updated photo
+	decode(t, rec, &updated)
+	if updated.ID != created.ID || updated.PhotoURL != "http://example.com/a-edited.jpg" {
+		t.Fatalf("updated photo = %+v, want photo %d with the new URL", updated, created.ID)
+	}
+
+	expectStatus(t, ts.do("DELETE", path, token, nil), http.StatusOK)
+	expectProblem(t, ts.do("GET", path, token, nil), http.StatusNotFound, "PHOTO_NOT_FOUND")
+}
+
+func TestCreatePhotoRejectsInvalidURL(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t)
+	_, token := ts.signUp("alice", "alice@example.com")
+
+	p := expectProblem(t, ts.do("POST", "/api/photos", token, map[string]string{"photo_url": "not a url"}), http.StatusUnprocessableEntity, "VALIDATION_FAILED")
+	if len(p.Errors) == 0 || p.Errors[0].Field != "photo_url" {
+		t.Errorf("errors = %+v, want an error for photo_url", p.Errors)
+	}
+}
+
+func TestPhotosOfOtherUsersAreNotFound(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t)
+	_, alice := ts.signUp("alice", "alice@example.com")
+	_, bob := ts.signUp("bob", "bob@example.com")
+
+	photo := ts.createPhoto(alice, "http://example.com/a.jpg")
+	path := fmt.Sprintf("/api/photos/%d", photo.ID)
+
+	expectProblem(t, ts.do("GET", path, bob, nil), http.StatusNotFound, "PHOTO_NOT_FOUND")
+	expectProblem(t, ts.do("PUT", path, bob, map[string]string{"photo_url": "http://example.com/b.jpg"}), http.StatusNotFound, "PHOTO_NOT_FOUND")
+	expectProblem(t, ts.do("DELETE", path, bob, nil), http.StatusNotFound, "PHOTO_NOT_FOUND")
+	expectProblem(t, ts.do("POST", path+"/profile", bob, nil), http.StatusNotFound, "PHOTO_NOT_FOUND")
+
+	rec := ts.do("GET", path, alice, nil)
+	expectStatus(t, rec, http.StatusOK)
+	if !strings.Contains(rec.Body.String(), "http://example.com/a.jpg") {
+		t.Errorf("photo was changed by another user: %s", rec.Body.String())
+	}
+}
+
+func TestUploadedPhotosOfOtherUsersCannotBeReferenced(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t)
+	_, alice := ts.signUp("alice", "alice@example.com")
+	_, bob := ts.signUp("bob", "bob@example.com")
+
+	uploaded := ts.upload(alice)
+	file := ts.storedFile(uploaded.PhotoURL)
+	if !fileExists(file) {
+		t.Fatalf("uploaded file %s does not exist", file)
+	}
+
+	body := map[string]string{"photo_url": uploaded.PhotoURL}
+	bobPhoto := ts.createPhoto(bob, "http://example.com/b.jpg")
+	requests := []struct {
+		name, method, path string
+	}{
+		{"create", "POST", "/api/photos"},
+		{"set profile", "POST", "/api/photos/profile"},
+		{"update", "PUT", fmt.Sprintf("/api/photos/%d", bobPhoto.ID)},
+	}
+	for _, r := range requests {
+		t.Run(r.name, func(t *testing.T) {
+			p := expectProblem(t, ts.do(r.method, r.path, bob, body), http.StatusUnprocessableEntity, "VALIDATION_FAILED")
+			if len(p.Errors) == 0 || p.Errors[0].Field != "photo_url" || p.Errors[0].Rule != "owner" {
+				t.Errorf("errors = %+v, want an owner error for photo_url", p.Errors)
+			}
+		})
+	}
+
+	// Keys that escape the uploads of the caller are rejected too
+	escaping := ts.files.BaseURL + "/photos/2/../1/" + filepath.Base(file)
+	expectProblem(t, ts.do("POST", "/api/photos", bob, map[string]string{"photo_url": escaping}), http.StatusUnprocessableEntity, "VALIDATION_FAILED")
+
+	if !fileExists(file) {
+		t.Fatal("another user's requests removed the uploaded file")
+	}
+}
+
+func TestUploadedPhotoIsServedAndCanBecomeProfilePhoto(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t)
+	userID, token := ts.signUp("alice", "alice@example.com")
+
+	uploaded := ts.upload(token)
+	prefix := fmt.Sprintf("%s/uploads/photos/%d/", ts.server.Config.Server.PublicURL, userID)
+	if !strings.HasPrefix(uploaded.PhotoURL, prefix) {
+		t.Fatalf("photo_url = %s, want an absolute URL below %s", uploaded.PhotoURL, prefix)
+	}
+
+	rec := ts.do("GET", strings.TrimPrefix(uploaded.PhotoURL, ts.server.Config.Server.PublicURL), "", nil)
+	expectStatus(t, rec, http.StatusOK)
+	if !bytes.Equal(rec.Body.Bytes(), pngHeader) {
+		t.Errorf("served file = %q, want the uploaded bytes", rec.Body.Bytes())
+	}
+	expectStatus(t, ts.do("GET", "/uploads/photos/", "", nil), http.StatusNotFound)
+
+	rec = ts.do("POST", "/api/photos/profile", token, map[string]string{"photo_url": uploaded.PhotoURL})
+	expectStatus(t, rec, http.StatusOK)
+	rec = ts.do("GET", "/api/photos/profile", token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	if !strings.Contains(rec.Body.String(), uploaded.PhotoURL) {
+		t.Errorf("profile photo = %s, want %s", rec.Body.String(), uploaded.PhotoURL)
+	}
+
+	// URLs saved while uploads were addressed by their path alone are still accepted
+	relative := strings.TrimPrefix(uploaded.PhotoURL, ts.server.Config.Server.PublicURL)
+	ts.createPhoto(token, relative)
+}
+
+func TestDeletePhotoKeepsFilesStillInUse(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t)
+	_, token := ts.signUp("alice", "alice@example.com")
+
+	uploaded := ts.upload(token)
+	file := ts.storedFile(uploaded.PhotoURL)
+	copied := ts.createPhoto(token, uploaded.PhotoURL)
+
+	expectStatus(t, ts.do("DELETE", fmt.Sprintf("/api/photos/%d", uploaded.ID), token, nil), http.StatusOK)
+	if !fileExists(file) {
+		t.Fatal("file was removed while another photo still uses it")
+	}
+
+	expectStatus(t, ts.do("DELETE", fmt.Sprintf("/api/photos/%d", copied.ID), token, nil), http.StatusOK)
+	if fileExists(file) {
+		t.Fatal("file of the last photo using it was not removed")
+	}
+}
+
+func TestProfilePhoto(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t)
+	_, token := ts.signUp("alice", "alice@example.com")
+
+	first := ts.createPhoto(token, "http://example.com/a.jpg")
+	second := ts.createPhoto(token, "http://example.com/b.jpg")
+
+	for _, p := range []photo{first, second} {
+		rec := ts.do("POST", fmt.Sprintf("/api/photos/%d/profile", p.ID), token, nil)
+		expectStatus(t, rec, http.StatusOK)
+
+		rec = ts.do("GET", "/api/photos/profile", token, nil)
+		expectStatus(t, rec, http.StatusOK)
+		var profile struct {
+			PhotoURL string `json:"photo_url"`
+		}
+		decode(t, rec, &profile)
+		if profile.PhotoURL != p.PhotoURL {
+			t.Fatalf("profile photo = %s, want %s", profile.PhotoURL, p.PhotoURL)
+		}
+	}
+
+	// The previous profile photo went back to the gallery
+	rec := ts.do("GET", fmt.Sprintf("/api/photos/%d", first.ID), token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	var demoted photo
+	decode(t, rec, &demoted)
+	if demoted.IsProfile {
+		t.Error("user has two profile photos")
+	}
+}
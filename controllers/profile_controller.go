@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"backend-api/apierror"
+	"backend-api/app"
+	"backend-api/helpers"
+	"backend-api/middlewares"
+	"backend-api/models"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// GetUser returns the public profile of a user.
+// Supports "?include=photos" to embed all of the user's photos.
+func (s *Server) GetUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := parseUserID(w, r)
+	if !ok {
+		return
+	}
+
+	user, includePhotos, ok := s.loadProfile(w, r, userID)
+	if !ok {
+		return
+	}
+
+	view := newPublicUserView(user)
+	if includePhotos {
+		view.Photos = &user.Photos
+	}
+	helpers.RespondWithJSON(w, http.StatusOK, view)
+}
+
+// GetCurrentUser returns the account of the caller, including private fields.
+// Supports "?include=photos" like GetUser.
+func (s *Server) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middlewares.GetUserContextKey(r)
+	if !ok {
+		apierror.Write(w, r, apierror.ErrAuthTokenInvalid)
+		return
+	}
+
+	user, includePhotos, ok := s.loadProfile(w, r, userID)
+	if !ok {
+		return
+	}
+
+	view := newUserView(user)
+	if includePhotos {
+		view.Photos = &user.Photos
+	}
+	helpers.RespondWithJSON(w, http.StatusOK, view)
+}
+
+// loadProfile reads a user together with its photos in a single query. Without
+// "?include=photos" only the profile photo is loaded.
+func (s *Server) loadProfile(w http.ResponseWriter, r *http.Request, userID uint) (app.User, bool, bool) {
+	includePhotos := false
+	if include := r.URL.Query().Get("include"); include != "" {
+		for _, field := range strings.Split(include, ",") {
+			if strings.TrimSpace(field) != "photos" {
+				apierror.Write(w, r, apierror.ErrInvalidRequest.WithDetail("include only supports \"photos\""))
+				return app.User{}, false, false
+			}
+			includePhotos = true
+		}
+	}
+
+	user, err := s.Users.GetUserWithPhotos(userID, !includePhotos)
+	if errors.Is(err, models.ErrNotFound) || err == nil && user.DeletedAt != nil {
+		apierror.Write(w, r, apierror.ErrUserNotFound)
+		return app.User{}, false, false
+	}
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal("Error fetching user", err))
+		return app.User{}, false, false
+	}
+	return user, includePhotos, true
+}
+
+// parseUserID reads the userId path parameter. Routes only match digits, so an
+// unparsable ID is out of range and cannot belong to any user.
+func parseUserID(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	userID, err := strconv.ParseUint(mux.Vars(r)["userId"], 10, 32)
+	if err != nil {
+		apierror.Write(w, r, apierror.ErrUserNotFound)
+		return 0, false
+	}
+	return uint(userID), true
+}
@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"backend-api/apierror"
+	"backend-api/redact"
+	"backend-api/validation"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// decodeRequest decodes the JSON body of r into v and validates v against its
+// binding tags. It responds with 400 for malformed JSON and 422 for invalid
+// fields, and reports whether the handler may continue.
+func decodeRequest(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		apierror.Write(w, r, apierror.ErrInvalidRequest)
+		return false
+	}
+
+	var errs validation.Errors
+	if err := validation.Struct(v); errors.As(err, &errs) {
+		apierror.Write(w, r, errs)
+		return false
+	}
+	return true
+}
+
+// Request bodies are decoded into these types rather than into app.User, so a
+// client cannot set fields such as id or email_verified_at. Passwords and tokens
+// are redact.Secret values and cannot end up in logs.
+
+// registerRequest is the body of POST /users/register
+type registerRequest struct {
+	Username string        `json:"username" binding:"required,max=255"`
+	Email    string        `json:"email" binding:"required,email,max=255"`
+	Password redact.Secret `json:"password" binding:"required,min=6"`
+}
+
+// loginRequest is the body of POST /users/login
+type loginRequest struct {
+	Email    string        `json:"email" binding:"required,email"`
+	Password redact.Secret `json:"password" binding:"required"`
+}
+
+// updateUserRequest is the body of PATCH /users/{userId}. Omitted fields are
+// left unchanged; changing email or password requires current_password. The
+// deprecated PUT also requires username and email, see checkReplaceUser.
+type updateUserRequest struct {
+	Username        *string        `json:"username" binding:"omitempty,max=255"`
+	Email           *string        `json:"email" binding:"omitempty,email,max=255"`
+	Password        *redact.Secret `json:"password" binding:"omitempty,min=6"`
+	CurrentPassword redact.Secret  `json:"current_password"`
+}
+
+// checkReplaceUser responds with 422 when a PUT /users/{userId} body lacks a
+// field required by a full replace, and reports whether the handler may continue
+func checkReplaceUser(w http.ResponseWriter, r *http.Request, input updateUserRequest) bool {
+	var errs validation.Errors
+	if input.Username == nil {
+		errs = append(errs, validation.FieldError{Field: "username", Rule: "required", Message: "username is required"})
+	}
+	if input.Email == nil {
+		errs = append(errs, validation.FieldError{Field: "email", Rule: "required", Message: "email is required"})
+	}
+	if len(errs) > 0 {
+		apierror.Write(w, r, errs)
+		return false
+	}
+	return true
+}
+
+// checkUsername responds with 422 when username is blank once trimmed, which the
+// required rule does not catch, and reports whether the handler may continue
+func checkUsername(w http.ResponseWriter, r *http.Request, username string) bool {
+	if strings.TrimSpace(username) == "" {
+		apierror.Write(w, r, validation.Errors{{
+			Field:   "username",
+			Rule:    "required",
+			Message: "username is required",
+		}})
+		return false
+	}
+	return true
+}
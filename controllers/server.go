@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"backend-api/accounts"
+	"backend-api/config"
+	"backend-api/loginguard"
+	"backend-api/mailer"
+	"backend-api/models"
+	"backend-api/revocation"
+	"backend-api/storage"
+	"context"
+	"sync"
+	"time"
+)
+
+// Server holds the dependencies shared by the HTTP handlers.
+// Handlers are methods on Server so they can be tested with in-memory repositories.
+type Server struct {
+	Users       models.UserRepository
+	Photos      models.PhotoRepository
+	Tokens      models.TokenRepository
+	Storage     storage.Storage
+	Mailer      mailer.Mailer
+	Revocations *revocation.List
+	Accounts    *accounts.StatusCache
+	// LoginGuard limits password attempts; defaults to an in-memory store
+	LoginGuard *loginguard.Guard
+
+	// Config holds the settings of the handlers, such as token lifetimes and
+	// the base URL of links sent by email
+	Config config.Config
+
+	// background tracks work that outlives its request, such as sending emails
+	background sync.WaitGroup
+}
+
+// accountStatusTTL is how long the status of a user is cached by Server.Accounts
+const accountStatusTTL = 30 * time.Second
+
+// NewServer creates a Server configured by cfg whose revocation list is backed
+// by tokens and whose account status cache is backed by users
+func NewServer(cfg config.Config, users models.UserRepository, photos models.PhotoRepository, tokens models.TokenRepository, store storage.Storage, mail mailer.Mailer) *Server {
+	return &Server{
+		Users:       users,
+		Photos:      photos,
+		Tokens:      tokens,
+		Storage:     store,
+		Mailer:      mail,
+		Config:      cfg,
+		Revocations: revocation.NewList(tokens, cfg.JWT.AccessTokenTTL),
+		Accounts:    accounts.NewStatusCache(users, accountStatusTTL),
+		LoginGuard:  loginguard.New(loginguard.NewMemoryStore()),
+	}
+}
+
+// goBackground runs fn in a goroutine that Wait waits for
+func (s *Server) goBackground(fn func()) {
+	s.background.Add(1)
+	go func() {
+		defer s.background.Done()
+		fn()
+	}()
+}
+
+// Wait blocks until the background work started by handlers has finished, or
+// returns ctx.Err() when ctx is done first. Call it after the HTTP server has
+// shut down, so no new work is started.
+func (s *Server) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.background.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
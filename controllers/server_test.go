@@ -0,0 +1,189 @@
+package controllers_test
+
+import (
+	"backend-api/config"
+	"backend-api/controllers"
+	"backend-api/helpers"
+	"backend-api/mailer"
+	"backend-api/models"
+	"backend-api/router"
+	"backend-api/storage"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// Handlers log every request; keep the test output readable
+	log.SetOutput(io.Discard)
+	helpers.InitJWTKeys(config.JWT{SigningAlg: "HS256", Secret: "test-secret"})
+	os.Exit(m.Run())
+}
+
+// testServer serves the router backed by in-memory repositories and the
+// default local storage in a temporary directory, so the handlers run without
+// a database
+type testServer struct {
+	t       *testing.T
+	server  *controllers.Server
+	handler http.Handler
+	files   *storage.LocalStorage
+	repos   *models.MemoryStore
+	outbox  *outbox
+}
+
+// outbox is a mailer that keeps the sent messages
+type outbox struct {
+	mu       sync.Mutex
+	messages []mailer.Message
+}
+
+func (o *outbox) Send(ctx context.Context, msg mailer.Message) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.messages = append(o.messages, msg)
+	return nil
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	cfg := config.Default()
+	cfg.Server.PublicURL = "http://localhost:3000"
+	cfg.JWT.Secret = "test-secret"
+	cfg.RateLimits = config.RateLimits{}
+
+	cfg.Storage.LocalDir = t.TempDir()
+
+	store, err := storage.New(cfg.Storage, cfg.Server.PublicURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := store.(*storage.LocalStorage)
+
+	repos := models.NewMemoryStore()
+	mail := &outbox{}
+	server := controllers.NewServer(cfg, repos, repos, repos, files, mail)
+	t.Cleanup(func() { server.Wait(context.Background()) })
+
+	return &testServer{t: t, server: server, handler: router.NewRouter(server), files: files, repos: repos, outbox: mail}
+}
+
+// do sends a request with body encoded as JSON, authenticated with token when set
+func (ts *testServer) do(method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	ts.t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			ts.t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	return ts.send(req, token)
+}
+
+// send serves req, authenticated with token when set
+func (ts *testServer) send(req *http.Request, token string) *httptest.ResponseRecorder {
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	ts.handler.ServeHTTP(rec, req)
+	return rec
+}
+
+// signUp registers a user and logs in, returning the user ID and access token
+func (ts *testServer) signUp(username, email string) (uint, string) {
+	ts.t.Helper()
+
+	rec := ts.do("POST", "/users/register", "", map[string]string{"username": username, "email": email, "password": "secret123"})
+	expectStatus(ts.t, rec, http.StatusCreated)
+	var user struct {
+		ID uint `json:"id"`
+	}
+	decode(ts.t, rec, &user)
+
+	return user.ID, ts.login(email).Token
+}
+
+// tokens is the response of a login or token refresh
+type tokens struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// login logs in with the password used by signUp
+func (ts *testServer) login(email string) tokens {
+	ts.t.Helper()
+
+	rec := ts.do("POST", "/users/login", "", map[string]string{"email": email, "password": "secret123"})
+	expectStatus(ts.t, rec, http.StatusOK)
+	var t tokens
+	decode(ts.t, rec, &t)
+	return t
+}
+
+// sentTo waits for the emails being sent and returns the last one sent to address
+func (ts *testServer) sentTo(address string) mailer.Message {
+	ts.t.Helper()
+
+	if err := ts.server.Wait(context.Background()); err != nil {
+		ts.t.Fatal(err)
+	}
+	ts.outbox.mu.Lock()
+	defer ts.outbox.mu.Unlock()
+	for i := len(ts.outbox.messages) - 1; i >= 0; i-- {
+		if ts.outbox.messages[i].To == address {
+			return ts.outbox.messages[i]
+		}
+	}
+	ts.t.Fatalf("no email sent to %s", address)
+	return mailer.Message{}
+}
+
+// problem is the part of an RFC 7807 error response checked by the tests
+type problem struct {
+	Status int    `json:"status"`
+	Code   string `json:"code"`
+	Errors []struct {
+		Field string `json:"field"`
+		Rule  string `json:"rule"`
+	} `json:"errors"`
+}
+
+func decode(t *testing.T, rec *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("decoding response %q: %v", rec.Body.String(), err)
+	}
+}
+
+func expectStatus(t *testing.T, rec *httptest.ResponseRecorder, status int) {
+	t.Helper()
+	if rec.Code != status {
+		t.Fatalf("status = %d, want %d; body: %s", rec.Code, status, rec.Body.String())
+	}
+}
+
+// expectProblem checks the status and error code of an error response
+func expectProblem(t *testing.T, rec *httptest.ResponseRecorder, status int, code string) problem {
+	t.Helper()
+	expectStatus(t, rec, status)
+	var p problem
+	decode(t, rec, &p)
+	if p.Code != code {
+		t.Fatalf("code = %q, want %q; body: %s", p.Code, code, rec.Body.String())
+	}
+	return p
+}
@@ -1,12 +1,17 @@
 package controllers
 
 import (
+	"backend-api/apierror"
 	"backend-api/app"
 	"backend-api/helpers"
 	"backend-api/middlewares"
 	"backend-api/models"
+	"backend-api/redact"
+	"backend-api/validation"
 	"encoding/json"
+	"errors"
 	"log"
+	"math"
 	"net/http"
 	"strconv"
 	"time"
@@ -15,126 +20,435 @@ import (
 )
 
 // RegisterUser handles the registration of a new user
-func RegisterUser(w http.ResponseWriter, r *http.Request) {
-	var user app.User
-	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		helpers.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+func (s *Server) RegisterUser(w http.ResponseWriter, r *http.Request) {
+	var input registerRequest
+	if !decodeRequest(w, r, &input) || !checkUsername(w, r, input.Username) {
 		return
 	}
 
 	// Hash the password
-	hashedPassword, err := helpers.HashPassword(user.Password)
+	hashedPassword, err := helpers.HashPassword(input.Password)
 	if err != nil {
-		helpers.RespondWithError(w, http.StatusInternalServerError, "Error hashing password")
+		apierror.Write(w, r, apierror.Internal("Error hashing password", err))
 		return
 	}
-	user.Password = hashedPassword
-	user.CreatedAt = time.Now()
-	user.UpdatedAt = time.Now()
+	user := app.User{
+		Username:  input.Username,
+		Email:     input.Email,
+		Password:  hashedPassword,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
 
 	// Save the user to the database
-	if err := models.CreateUser(&user); err != nil {
-		helpers.RespondWithError(w, http.StatusInternalServerError, "Error saving user")
+	if err := s.Users.CreateUser(&user); err != nil {
+		apierror.Write(w, r, apierror.Internal("Error saving user", err))
 		return
 	}
 
-	helpers.RespondWithJSON(w, http.StatusCreated, user)
+	// New accounts start unverified
+	s.sendVerificationEmail(user)
+
+	helpers.RespondWithJSON(w, http.StatusCreated, newUserView(user))
 }
 
 // LoginUser handles user login and JWT generation
-func LoginUser(w http.ResponseWriter, r *http.Request) {
-	var user app.User
-	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		helpers.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+func (s *Server) LoginUser(w http.ResponseWriter, r *http.Request) {
+	var user loginRequest
+	if !decodeRequest(w, r, &user) {
 		return
 	}
 
-	log.Printf("Login attempt with email: %s", user.Email)
+	log.Printf("Login attempt with email: %s", redact.Email(user.Email))
 
-	storedUser, err := models.GetUserByEmail(user.Email)
-	if err != nil {
-		log.Printf("Error retrieving user by email: %v", err)
-		helpers.RespondWithError(w, http.StatusUnauthorized, "Invalid email or password")
+	storedUser, ok := s.checkCredentials(w, r, user)
+	if !ok {
 		return
 	}
 
-	log.Printf("Stored user: %v", storedUser)
+	if err := s.accountStatusError(storedUser); err != nil {
+		log.Printf("Login attempt for %s user ID %d", storedUser.Status(), storedUser.ID)
+		apierror.Write(w, r, err)
+		return
+	}
+
+	if s.Config.Accounts.RequireVerifiedEmail && storedUser.EmailVerifiedAt == nil {
+		apierror.Write(w, r, apierror.ErrEmailNotVerified)
+		return
+	}
 
-	if !helpers.CheckPasswordHash(user.Password, storedUser.Password) {
-		log.Printf("Password mismatch for user: %s", user.Email)
-		helpers.RespondWithError(w, http.StatusUnauthorized, "Invalid email or password")
+	// Every login starts a new refresh token family
+	familyID, err := helpers.RandomID()
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal("Error generating token", err))
 		return
 	}
 
-	token, err := helpers.GenerateJWT(storedUser.ID)
+	refreshToken := app.RefreshToken{UserID: storedUser.ID, FamilyID: familyID}
+	tokens, err := s.issueTokens(&refreshToken, s.Tokens.CreateRefreshToken)
 	if err != nil {
-		log.Printf("Error generating token: %v", err)
-		helpers.RespondWithError(w, http.StatusInternalServerError, "Error generating token")
+		apierror.Write(w, r, apierror.Internal("Error generating token", err))
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, tokens)
+}
+
+// RefreshToken exchanges a refresh token for a new access token and refresh token.
+// The presented refresh token is rotated: it cannot be used again, and reusing it
+// revokes every refresh token issued from the same login.
+func (s *Server) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		RefreshToken redact.Secret `json:"refresh_token" binding:"required"`
+	}
+	if !decodeRequest(w, r, &input) {
 		return
 	}
 
-	helpers.RespondWithJSON(w, http.StatusOK, map[string]string{"token": token})
+	var next app.RefreshToken
+	tokens, err := s.issueTokens(&next, func(token *app.RefreshToken) error {
+		return s.Tokens.RotateRefreshToken(helpers.HashToken(input.RefreshToken.Reveal()), token)
+	})
+	switch {
+	case errors.Is(err, models.ErrRefreshTokenReused):
+		log.Printf("Refresh token reuse detected, token family revoked")
+		apierror.Write(w, r, apierror.ErrRefreshTokenInvalid)
+	case errors.Is(err, models.ErrRefreshTokenInvalid):
+		apierror.Write(w, r, apierror.ErrRefreshTokenInvalid)
+	case err != nil:
+		apierror.Write(w, r, apierror.Internal("Error generating token", err))
+	default:
+		helpers.RespondWithJSON(w, http.StatusOK, tokens)
+	}
+}
+
+// Logout revokes the access token used for the request.
+// If the body contains a refresh_token, every refresh token of that login is revoked too.
+func (s *Server) Logout(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middlewares.GetUserContextKey(r)
+	if !ok {
+		apierror.Write(w, r, apierror.ErrAuthTokenInvalid)
+		return
+	}
+	token, ok := middlewares.GetTokenInfo(r)
+	if !ok {
+		apierror.Write(w, r, apierror.ErrAuthTokenInvalid)
+		return
+	}
+
+	// The body is optional
+	var input struct {
+		RefreshToken redact.Secret `json:"refresh_token"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			apierror.Write(w, r, apierror.ErrInvalidRequest)
+			return
+		}
+	}
+
+	if err := s.Revocations.RevokeToken(token.ID, userID, token.ExpiresAt); err != nil {
+		apierror.Write(w, r, apierror.Internal("Error logging out", err))
+		return
+	}
+
+	if input.RefreshToken != "" {
+		if err := s.Tokens.RevokeRefreshTokenFamily(helpers.HashToken(input.RefreshToken.Reveal()), userID); err != nil {
+			apierror.Write(w, r, apierror.Internal("Error logging out", err))
+			return
+		}
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, map[string]string{"result": "success"})
+}
+
+// LogoutAll revokes every access token and refresh token of the user, logging out all sessions.
+func (s *Server) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middlewares.GetUserContextKey(r)
+	if !ok {
+		apierror.Write(w, r, apierror.ErrAuthTokenInvalid)
+		return
+	}
+
+	if err := s.Revocations.RevokeAllForUser(userID); err != nil {
+		apierror.Write(w, r, apierror.Internal("Error logging out", err))
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, map[string]string{"result": "success"})
+}
+
+// tokenResponse is returned by LoginUser and RefreshToken
+type tokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// issueTokens generates a new refresh token, persists it with save (which fills
+// in refreshToken.UserID when rotating) and signs an access token for its user.
+// The user is read again so the access token reflects its current state, such as
+// its role; suspended and deleted users get the error of accountStatusError.
+func (s *Server) issueTokens(refreshToken *app.RefreshToken, save func(*app.RefreshToken) error) (*tokenResponse, error) {
+	plain, hash, err := helpers.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken.TokenHash = hash
+	refreshToken.CreatedAt = time.Now()
+	refreshToken.ExpiresAt = refreshToken.CreatedAt.Add(s.Config.JWT.RefreshTokenTTL)
+	if err := save(refreshToken); err != nil {
+		return nil, err
+	}
+
+	user, err := s.Users.GetUserByID(refreshToken.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.accountStatusError(user); err != nil {
+		return nil, err
+	}
+
+	accessToken, err := helpers.GenerateJWT(user, s.Config.JWT.AccessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tokenResponse{
+		Token:        accessToken,
+		RefreshToken: plain,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(s.Config.JWT.AccessTokenTTL.Seconds()),
+	}, nil
 }
 
-// UpdateUser handles updating user information
-func UpdateUser(w http.ResponseWriter, r *http.Request) {
-	var user app.User
+// UpdateUser applies a partial update to the caller's account and responds with the stored user.
+// Changing the email or password requires the current password.
+//
+// PUT is deprecated in favor of PATCH. It keeps its full replace contract, so
+// username and email are required, and its responses carry a Deprecation header.
+func (s *Server) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	replace := r.Method == http.MethodPut
+	if replace {
+		w.Header().Set("Deprecation", "true")
+	}
+
+	var input updateUserRequest
 	params := mux.Vars(r)
 	userID := params["userId"]
 
 	// Retrieve user ID from request context
 	ctxUserID, ok := middlewares.GetUserContextKey(r)
 	if !ok {
-		helpers.RespondWithError(w, http.StatusUnauthorized, "Invalid user ID in token")
+		apierror.Write(w, r, apierror.ErrAuthTokenInvalid)
 		return
 	}
 
 	// Check if the requested user ID matches the user ID from the token
 	if strconv.Itoa(int(ctxUserID)) != userID {
-		helpers.RespondWithError(w, http.StatusForbidden, "You can only update your own account")
+		apierror.Write(w, r, apierror.ErrForbidden.WithDetail("You can only update your own account"))
+		return
+	}
+
+	if !decodeRequest(w, r, &input) {
+		return
+	}
+	if replace && !checkReplaceUser(w, r, input) {
+		return
+	}
+	if input.Username != nil && !checkUsername(w, r, *input.Username) {
 		return
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		helpers.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+	current, err := s.Users.GetUserByID(ctxUserID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal("Error updating user", err))
 		return
 	}
 
-	user.UpdatedAt = time.Now()
+	emailChanged := input.Email != nil && models.NormalizeEmail(*input.Email) != current.Email
+	passwordChanged := input.Password != nil
+
+	// Sensitive changes must be confirmed with the current password
+	if emailChanged || passwordChanged {
+		if input.CurrentPassword == "" {
+			apierror.Write(w, r, validation.Errors{{
+				Field:   "current_password",
+				Rule:    "required",
+				Message: "current_password is required to change email or password",
+			}})
+			return
+		}
+		if !helpers.CheckPasswordHash(input.CurrentPassword, current.Password) {
+			apierror.Write(w, r, apierror.ErrCurrentPasswordInvalid)
+			return
+		}
+	}
+
+	update := app.UserUpdate{Username: input.Username, Email: input.Email, UpdatedAt: time.Now()}
+
+	// Hash the password if it's provided
+	if passwordChanged {
+		hashedPassword, err := helpers.HashPassword(*input.Password)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal("Error hashing password", err))
+			return
+		}
+		update.Password = &hashedPassword
+	}
 
-	if err := models.UpdateUser(userID, &user); err != nil {
-		helpers.RespondWithError(w, http.StatusInternalServerError, "Error updating user")
+	if err := s.Users.UpdateUser(ctxUserID, update); err != nil {
+		apierror.Write(w, r, apierror.Internal("Error updating user", err))
 		return
 	}
 
-	// Set the user ID to the ID from the URL
-	user.ID = ctxUserID
+	// A new password logs out every existing session
+	if passwordChanged {
+		if err := s.Revocations.RevokeAllForUser(ctxUserID); err != nil {
+			log.Printf("Error revoking sessions for user ID %d: %v", ctxUserID, err)
+		}
+	}
 
-	helpers.RespondWithJSON(w, http.StatusOK, user)
+	// Respond with the stored row rather than the request
+	user, err := s.Users.GetUserWithPhotos(ctxUserID, true)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal("Error updating user", err))
+		return
+	}
+
+	// A new email address has to be verified again
+	if emailChanged {
+		s.sendVerificationEmail(user)
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, newUserView(user))
 }
 
 // DeleteUser handles deleting a user
-func DeleteUser(w http.ResponseWriter, r *http.Request) {
+func (s *Server) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	userID := params["userId"]
 
 	// Retrieve user ID from request context
 	ctxUserID, ok := middlewares.GetUserContextKey(r)
 	if !ok {
-		helpers.RespondWithError(w, http.StatusUnauthorized, "Invalid user ID in token")
+		apierror.Write(w, r, apierror.ErrAuthTokenInvalid)
 		return
 	}
 
 	// Check if the requested user ID matches the user ID from the token
 	if strconv.Itoa(int(ctxUserID)) != userID {
-		helpers.RespondWithError(w, http.StatusForbidden, "You can only delete your own account")
+		apierror.Write(w, r, apierror.ErrForbidden.WithDetail("You can only delete your own account"))
 		return
 	}
 
-	if err := models.DeleteUser(userID); err != nil {
-		helpers.RespondWithError(w, http.StatusInternalServerError, "Error deleting user")
+	restoreUntil, err := s.deleteAccount(ctxUserID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal("Error deleting user", err))
 		return
 	}
 
-	helpers.RespondWithJSON(w, http.StatusOK, map[string]string{"result": "success"})
+	helpers.RespondWithJSON(w, http.StatusOK, map[string]interface{}{"result": "success", "restore_until": restoreUntil})
+}
+
+// RestoreAccount undoes the deletion of the caller's account during the restore
+// window. Since deleted users cannot log in, the body carries the credentials.
+func (s *Server) RestoreAccount(w http.ResponseWriter, r *http.Request) {
+	var input loginRequest
+	if !decodeRequest(w, r, &input) {
+		return
+	}
+
+	user, ok := s.checkCredentials(w, r, input)
+	if !ok {
+		return
+	}
+
+	if user.DeletedAt == nil {
+		apierror.Write(w, r, apierror.ErrAccountNotDeleted)
+		return
+	}
+	if time.Since(*user.DeletedAt) >= s.Config.Accounts.RestoreWindow {
+		apierror.Write(w, r, apierror.ErrAccountDeleted.WithDetail("The restore window of this account has passed"))
+		return
+	}
+
+	if err := s.Users.RestoreUser(user.ID); err != nil {
+		apierror.Write(w, r, apierror.Internal("Error restoring account", err))
+		return
+	}
+	s.Accounts.Forget(user.ID)
+
+	user, err := s.Users.GetUserWithPhotos(user.ID, true)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal("Error restoring account", err))
+		return
+	}
+	helpers.RespondWithJSON(w, http.StatusOK, newUserView(user))
+}
+
+// checkCredentials looks up the user with the email and password of input.
+// Attempts are counted per account and client IP before the password is
+// compared; after too many failures, the caller gets 429 with Retry-After.
+func (s *Server) checkCredentials(w http.ResponseWriter, r *http.Request, input loginRequest) (app.User, bool) {
+	account := models.NormalizeEmail(input.Email)
+	ip := middlewares.ClientIP(r)
+
+	attempt, wait := s.LoginGuard.Reserve(r.Context(), account, ip)
+	if wait > 0 {
+		log.Printf("Login attempts for %s from %s locked for %s", redact.Email(account), ip, wait)
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		apierror.Write(w, r, apierror.ErrTooManyLoginAttempts)
+		return app.User{}, false
+	}
+
+	user, err := s.Users.GetUserByEmail(account)
+	if err != nil && !errors.Is(err, models.ErrNotFound) {
+		attempt.Cancel(r.Context())
+		apierror.Write(w, r, apierror.Internal("Error retrieving user", err))
+		return app.User{}, false
+	}
+
+	if err != nil || !helpers.CheckPasswordHash(input.Password, user.Password) {
+		if err == nil {
+			log.Printf("Password mismatch for user ID %d", user.ID)
+		}
+		// The reserved attempt stays counted as a failure
+		apierror.Write(w, r, apierror.ErrInvalidCredentials)
+		return app.User{}, false
+	}
+
+	attempt.Succeed(r.Context())
+	return user, true
+}
+
+// deleteAccount soft deletes a user and logs out all of their sessions. It
+// returns the end of the restore window, after which the account is purged.
+func (s *Server) deleteAccount(userID uint) (time.Time, error) {
+	now := time.Now()
+	if err := s.Users.DeleteUser(userID, now); err != nil {
+		return time.Time{}, err
+	}
+	s.Accounts.Forget(userID)
+
+	// Tokens of a deleted account must stop working immediately
+	if err := s.Revocations.RevokeAllForUser(userID); err != nil {
+		log.Printf("Error revoking sessions for user ID %d: %v", userID, err)
+	}
+	return now.Add(s.Config.Accounts.RestoreWindow), nil
+}
+
+// accountStatusError returns the error for a user that may not sign in, or nil
+func (s *Server) accountStatusError(user app.User) error {
+	switch user.Status() {
+	case app.UserDeleted:
+		restoreUntil := user.DeletedAt.Add(s.Config.Accounts.RestoreWindow)
+		return apierror.ErrAccountDeleted.WithDetail("Account is deleted and can be restored until " + restoreUntil.UTC().Format(time.RFC3339))
+	case app.UserSuspended:
+		return apierror.ErrAccountSuspended
+	default:
+		return nil
+	}
 }
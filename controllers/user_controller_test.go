@@ -0,0 +1,227 @@
+package controllers_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegisterUser(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t)
+
+	rec := ts.do("POST", "/users/register", "", map[string]string{
+		"username": "alice",
+		"email":    " Alice@Example.com ",
+		"password": "secret123",
+	})
+	expectStatus(t, rec, http.StatusCreated)
+	if strings.Contains(rec.Body.String(), "password") {
+		t.Errorf("response exposes the password: %s", rec.Body.String())
+	}
+	var user struct {
+		ID       uint   `json:"id"`
+		Username string `json:"username"`
+		Email    string `json:"email"`
+	}
+	decode(t, rec, &user)
+	if user.ID == 0 || user.Username != "alice" || user.Email != "alice@example.com" {
+		t.Errorf("registered user = %+v, want alice with a normalized email", user)
+	}
+}
+
+func TestRegisterUserRejectsInvalidInput(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t)
+	ts.signUp("alice", "alice@example.com")
+
+	tests := []struct {
+		name   string
+		body   map[string]string
+		status int
+		code   string
+		field  string
+	}{
+		{"missing username", map[string]string{"email": "bob@example.com", "password": "secret123"}, http.StatusUnprocessableEntity, "VALIDATION_FAILED", "username"},
+		{"empty username", map[string]string{"username": "", "email": "bob@example.com", "password": "secret123"}, http.StatusUnprocessableEntity, "VALIDATION_FAILED", "username"},
+		{"blank username", map[string]string{"username": "  ", "email": "bob@example.com", "password": "secret123"}, http.StatusUnprocessableEntity, "VALIDATION_FAILED", "username"},
+		{"invalid email", map[string]string{"username": "bob", "email": "bob", "password": "secret123"}, http.StatusUnprocessableEntity, "VALIDATION_FAILED", "email"},
+		{"short password", map[string]string{"username": "bob", "email": "bob@example.com", "password": "123"}, http.StatusUnprocessableEntity, "VALIDATION_FAILED", "password"},
+		{"email taken", map[string]string{"username": "bob", "email": "ALICE@example.com", "password": "secret123"}, http.StatusConflict, "USER_EMAIL_TAKEN", ""},
+		{"username taken", map[string]string{"username": "Alice", "email": "bob@example.com", "password": "secret123"}, http.StatusConflict, "USER_USERNAME_TAKEN", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := expectProblem(t, ts.do("POST", "/users/register", "", tt.body), tt.status, tt.code)
+			if tt.field != "" && (len(p.Errors) == 0 || p.Errors[0].Field != tt.field) {
+				t.Errorf("errors = %+v, want an error for %s", p.Errors, tt.field)
+			}
+		})
+	}
+}
+
+func TestLoginUser(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t)
+	ts.signUp("alice", "alice@example.com")
+
+	rec := ts.do("POST", "/users/login", "", map[string]string{"email": "ALICE@example.com", "password": "secret123"})
+	expectStatus(t, rec, http.StatusOK)
+	var tokens struct {
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+		TokenType    string `json:"token_type"`
+	}
+	decode(t, rec, &tokens)
+	if tokens.Token == "" || tokens.RefreshToken == "" || tokens.TokenType != "Bearer" {
+		t.Fatalf("tokens = %+v, want an access and a refresh token", tokens)
+	}
+
+	// The access token authenticates the protected routes
+	expectStatus(t, ts.do("GET", "/users/me", tokens.Token, nil), http.StatusOK)
+}
+
+func TestLoginUserRejectsInvalidCredentials(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t)
+	ts.signUp("alice", "alice@example.com")
+
+	tests := []struct {
+		name string
+		body map[string]string
+	}{
+		{"wrong password", map[string]string{"email": "alice@example.com", "password": "wrong-password"}},
+		{"unknown email", map[string]string{"email": "nobody@example.com", "password": "secret123"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectProblem(t, ts.do("POST", "/users/login", "", tt.body), http.StatusUnauthorized, "INVALID_CREDENTIALS")
+		})
+	}
+}
+
+func TestProtectedRoutesRequireToken(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t)
+
+	expectProblem(t, ts.do("GET", "/users/me", "", nil), http.StatusUnauthorized, "AUTH_TOKEN_MISSING")
+	expectProblem(t, ts.do("GET", "/api/photos", "not-a-token", nil), http.StatusUnauthorized, "AUTH_TOKEN_INVALID")
+}
+
+func TestLogoutAllKeepsNewLoginsValid(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t)
+	_, oldToken := ts.signUp("alice", "alice@example.com")
+
+	expectStatus(t, ts.do("POST", "/users/logout/all", oldToken, nil), http.StatusOK)
+
+	rec := ts.do("POST", "/users/login", "", map[string]string{"email": "alice@example.com", "password": "secret123"})
+	expectStatus(t, rec, http.StatusOK)
+	var tokens struct {
+		Token string `json:"token"`
+	}
+	decode(t, rec, &tokens)
+
+	expectProblem(t, ts.do("GET", "/users/me", oldToken, nil), http.StatusUnauthorized, "AUTH_TOKEN_REVOKED")
+	expectStatus(t, ts.do("GET", "/users/me", tokens.Token, nil), http.StatusOK)
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t)
+	ts.signUp("alice", "alice@example.com")
+	first := ts.login("alice@example.com")
+	other := ts.login("alice@example.com")
+
+	refresh := func(refreshToken string) *httptest.ResponseRecorder {
+		return ts.do("POST", "/users/token/refresh", "", map[string]string{"refresh_token": refreshToken})
+	}
+
+	rec := refresh(first.RefreshToken)
+	expectStatus(t, rec, http.StatusOK)
+	var second tokens
+	decode(t, rec, &second)
+	if second.RefreshToken == "" || second.RefreshToken == first.RefreshToken {
+		t.Fatalf("refresh token = %q, want a new one", second.RefreshToken)
+	}
+	expectStatus(t, ts.do("GET", "/users/me", second.Token, nil), http.StatusOK)
+
+	// Reusing a rotated token revokes the whole family, including its successor
+	expectProblem(t, refresh(first.RefreshToken), http.StatusUnauthorized, "REFRESH_TOKEN_INVALID")
+	expectProblem(t, refresh(second.RefreshToken), http.StatusUnauthorized, "REFRESH_TOKEN_INVALID")
+
+	// Other logins are separate families
+	expectStatus(t, refresh(other.RefreshToken), http.StatusOK)
+}
+
+func TestLoginLockedAfterFailedAttempts(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t)
+	ts.server.LoginGuard.Account.Threshold = 2
+	ts.signUp("alice", "alice@example.com")
+
+	wrong := map[string]string{"email": "alice@example.com", "password": "wrong-password"}
+	// Threshold failures are free and the next one starts the lockout
+	for i := 0; i <= ts.server.LoginGuard.Account.Threshold; i++ {
+		expectProblem(t, ts.do("POST", "/users/login", "", wrong), http.StatusUnauthorized, "INVALID_CREDENTIALS")
+	}
+
+	// Even the right password is refused while locked
+	rec := ts.do("POST", "/users/login", "", map[string]string{"email": "alice@example.com", "password": "secret123"})
+	expectProblem(t, rec, http.StatusTooManyRequests, "TOO_MANY_LOGIN_ATTEMPTS")
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("Retry-After header is missing")
+	}
+}
+
+func TestSuccessfulLoginKeepsFailuresOfIP(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t)
+	ts.server.LoginGuard.IP.Threshold = 2
+	ts.signUp("alice", "alice@example.com")
+
+	// Failures against other accounts from the same client
+	for _, email := range []string{"bob@example.com", "carol@example.com"} {
+		rec := ts.do("POST", "/users/login", "", map[string]string{"email": email, "password": "secret123"})
+		expectProblem(t, rec, http.StatusUnauthorized, "INVALID_CREDENTIALS")
+	}
+
+	// Logging into an account it controls does not reset the client's counter
+	ts.login("alice@example.com")
+	rec := ts.do("POST", "/users/login", "", map[string]string{"email": "dave@example.com", "password": "secret123"})
+	expectProblem(t, rec, http.StatusUnauthorized, "INVALID_CREDENTIALS")
+
+	rec = ts.do("POST", "/users/login", "", map[string]string{"email": "alice@example.com", "password": "secret123"})
+	expectProblem(t, rec, http.StatusTooManyRequests, "TOO_MANY_LOGIN_ATTEMPTS")
+}
+
+func TestReplaceUserIsDeprecatedFullUpdate(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t)
+	userID, token := ts.signUp("alice", "alice@example.com")
+	path := fmt.Sprintf("/users/%d", userID)
+
+	rec := ts.do("PUT", path, token, map[string]string{"username": "alice2"})
+	p := expectProblem(t, rec, http.StatusUnprocessableEntity, "VALIDATION_FAILED")
+	if len(p.Errors) != 1 || p.Errors[0].Field != "email" || p.Errors[0].Rule != "required" {
+		t.Errorf("errors = %+v, want email required", p.Errors)
+	}
+	if rec.Header().Get("Deprecation") != "true" {
+		t.Error("PUT response has no Deprecation header")
+	}
+
+	rec = ts.do("PUT", path, token, map[string]string{"username": "alice2", "email": "alice@example.com"})
+	expectStatus(t, rec, http.StatusOK)
+	if rec.Header().Get("Deprecation") != "true" {
+		t.Error("PUT response has no Deprecation header")
+	}
+
+	// PATCH is not deprecated and leaves omitted fields alone
+	rec = ts.do("PATCH", path, token, map[string]string{"username": "alice3"})
+	expectStatus(t, rec, http.StatusOK)
+	if rec.Header().Get("Deprecation") != "" {
+		t.Error("PATCH response has a Deprecation header")
+	}
+}
@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"backend-api/apierror"
+	"backend-api/app"
+	"backend-api/helpers"
+	"backend-api/mailer"
+	"backend-api/models"
+	"backend-api/redact"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// mailTimeout bounds how long sending a single email may take
+const mailTimeout = 30 * time.Second
+
+// VerifyEmail marks the email address the verification token was sent to as verified
+func (s *Server) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		apierror.Write(w, r, apierror.ErrVerificationTokenInvalid)
+		return
+	}
+
+	err := s.Tokens.VerifyEmail(helpers.HashToken(token), time.Now())
+	if errors.Is(err, models.ErrEmailVerificationTokenInvalid) {
+		// Unknown or expired, or the account's email changed after the link was sent
+		apierror.Write(w, r, apierror.ErrVerificationTokenInvalid)
+		return
+	}
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal("Error verifying email", err))
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, map[string]string{"result": "success"})
+}
+
+// ResendVerification sends a new verification email to an unverified account.
+// The response does not reveal whether the email is registered.
+func (s *Server) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+	if !decodeRequest(w, r, &input) {
+		return
+	}
+
+	user, err := s.Users.GetUserByEmail(input.Email)
+	switch {
+	case err == nil && user.EmailVerifiedAt == nil && user.DeletedAt == nil:
+		s.sendVerificationEmail(user)
+	case err != nil && !errors.Is(err, models.ErrNotFound):
+		log.Printf("Error retrieving user by email: %v", err)
+	}
+
+	helpers.RespondWithJSON(w, http.StatusAccepted, map[string]string{"result": "If the account exists and is not verified, a verification email has been sent"})
+}
+
+// sendVerificationEmail sends the verification link for user in the background
+func (s *Server) sendVerificationEmail(user app.User) {
+	token, tokenHash, err := helpers.GenerateOpaqueToken()
+	if err != nil {
+		log.Printf("Error generating verification token for user ID %d: %v", user.ID, err)
+		return
+	}
+	now := time.Now()
+	err = s.Tokens.CreateEmailVerificationToken(&app.EmailVerificationToken{
+		UserID:    user.ID,
+		Email:     user.Email,
+		TokenHash: tokenHash,
+		ExpiresAt: now.Add(s.Config.Accounts.EmailVerificationTTL),
+		CreatedAt: now,
+	})
+	if err != nil {
+		log.Printf("Error storing verification token for user ID %d: %v", user.ID, err)
+		return
+	}
+
+	link := strings.TrimSuffix(s.Config.Server.PublicURL, "/") + "/users/verify?token=" + url.QueryEscape(token)
+	s.sendMail(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("%s\n\nPlease verify your email address by opening this link:\n\n%s\n\nThe link expires in %s.\n",
+			greeting(user), link, s.Config.Accounts.EmailVerificationTTL),
+	})
+}
+
+// greeting opens an email to user; the username is optional
+func greeting(user app.User) string {
+	if user.Username == "" {
+		return "Hi,"
+	}
+	return "Hi " + user.Username + ","
+}
+
+// sendMail sends msg without blocking the request; failures are logged
+func (s *Server) sendMail(msg mailer.Message) {
+	s.goBackground(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+		defer cancel()
+		if err := s.Mailer.Send(ctx, msg); err != nil {
+			log.Printf("Error sending mail to %s: %v", redact.Email(msg.To), err)
+		}
+	})
+}
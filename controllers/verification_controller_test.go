@@ -0,0 +1,61 @@
+package controllers_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"testing"
+)
+
+var verifyLink = regexp.MustCompile(`/users/verify\?token=(\S+)`)
+
+// verificationToken returns the token of the last verification link emailed to address
+func (ts *testServer) verificationToken(address string) string {
+	ts.t.Helper()
+
+	msg := ts.sentTo(address)
+	match := verifyLink.FindStringSubmatch(msg.Body)
+	if match == nil {
+		ts.t.Fatalf("no verification link in email %q", msg.Body)
+	}
+	token, err := url.QueryUnescape(match[1])
+	if err != nil {
+		ts.t.Fatal(err)
+	}
+	return token
+}
+
+func TestVerifyEmail(t *testing.T) {
+	ts := newTestServer(t)
+	userID, accessToken := ts.signUp("alice", "alice@example.com")
+	token := ts.verificationToken("alice@example.com")
+
+	// The link is an opaque token, not a signed token usable as an access token
+	rec := ts.do("GET", "/users/me", token, nil)
+	expectStatus(t, rec, http.StatusUnauthorized)
+
+	for i := 0; i < 2; i++ {
+		rec = ts.do("GET", "/users/verify?token="+url.QueryEscape(token), "", nil)
+		expectStatus(t, rec, http.StatusOK)
+	}
+	user, err := ts.repos.GetUserByID(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.EmailVerifiedAt == nil {
+		t.Fatal("email is not verified")
+	}
+
+	rec = ts.do("GET", "/users/verify?token=unknown", "", nil)
+	expectProblem(t, rec, http.StatusBadRequest, "VERIFICATION_TOKEN_INVALID")
+
+	// A link sent to the previous address stops working once the email changes
+	rec = ts.do("PATCH", fmt.Sprintf("/users/%d", userID), accessToken, map[string]string{"email": "alice@example.org", "current_password": "secret123"})
+	expectStatus(t, rec, http.StatusOK)
+	rec = ts.do("GET", "/users/verify?token="+url.QueryEscape(token), "", nil)
+	expectProblem(t, rec, http.StatusBadRequest, "VERIFICATION_TOKEN_INVALID")
+
+	rec = ts.do("GET", "/users/verify?token="+url.QueryEscape(ts.verificationToken("alice@example.org")), "", nil)
+	expectStatus(t, rec, http.StatusOK)
+}
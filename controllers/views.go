@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"backend-api/app"
+	"time"
+)
+
+// userView is the representation of a user in responses to the user themself. It
+// is built field by field so that new fields of app.User, such as secrets, are
+// not exposed by accident.
+type userView struct {
+	ID              uint         `json:"id"`
+	Username        string       `json:"username"`
+	Email           string       `json:"email"`
+	EmailVerifiedAt *time.Time   `json:"email_verified_at"`
+	Role            app.Role     `json:"role"`
+	ProfilePhotoURL *string      `json:"profile_photo_url"`
+	Photos          *[]app.Photo `json:"photos,omitempty"`
+	CreatedAt       time.Time    `json:"created_at"`
+	UpdatedAt       time.Time    `json:"updated_at"`
+}
+
+func newUserView(user app.User) userView {
+	return userView{
+		ID:              user.ID,
+		Username:        user.Username,
+		Email:           user.Email,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		Role:            user.Role,
+		ProfilePhotoURL: profilePhotoURL(user.Photos),
+		CreatedAt:       user.CreatedAt,
+		UpdatedAt:       user.UpdatedAt,
+	}
+}
+
+// publicUserView is the representation of a user shown to anyone. It leaves out
+// the email address and verification state.
+type publicUserView struct {
+	ID              uint         `json:"id"`
+	Username        string       `json:"username"`
+	ProfilePhotoURL *string      `json:"profile_photo_url"`
+	Photos          *[]app.Photo `json:"photos,omitempty"`
+	CreatedAt       time.Time    `json:"created_at"`
+}
+
+func newPublicUserView(user app.User) publicUserView {
+	return publicUserView{
+		ID:              user.ID,
+		Username:        user.Username,
+		ProfilePhotoURL: profilePhotoURL(user.Photos),
+		CreatedAt:       user.CreatedAt,
+	}
+}
+
+// profilePhotoURL returns the URL of the profile photo among photos, or nil if there is none
+func profilePhotoURL(photos []app.Photo) *string {
+	for _, photo := range photos {
+		if photo.IsProfile {
+			url := photo.PhotoURL
+			return &url
+		}
+	}
+	return nil
+}
+
+// adminUserView is the representation of a user shown to staff
+type adminUserView struct {
+	ID              uint           `json:"id"`
+	Username        string         `json:"username"`
+	Email           string         `json:"email"`
+	EmailVerifiedAt *time.Time     `json:"email_verified_at"`
+	Role            app.Role       `json:"role"`
+	Status          app.UserStatus `json:"status"`
+	SuspendedAt     *time.Time     `json:"suspended_at"`
+	DeletedAt       *time.Time     `json:"deleted_at"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+}
+
+func newAdminUserView(user app.User) adminUserView {
+	return adminUserView{
+		ID:              user.ID,
+		Username:        user.Username,
+		Email:           user.Email,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		Role:            user.Role,
+		Status:          user.Status(),
+		SuspendedAt:     user.SuspendedAt,
+		DeletedAt:       user.DeletedAt,
+		CreatedAt:       user.CreatedAt,
+		UpdatedAt:       user.UpdatedAt,
+	}
+}
@@ -1,33 +1,70 @@
 package database
 
 import (
+	"backend-api/config"
+	"context"
 	"database/sql"
+	"expvar"
 	"fmt"
 	"log"
-	"os"
+	"time"
 
 	_ "github.com/go-sql-driver/mysql"
-	_ "github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
 )
 
 var DB *sql.DB
 
-func Init() {
+// Init connects to the database selected by cfg.Driver (mysql, postgres or
+// sqlite) and configures its connection pool. A database that is not reachable
+// yet, e.g. one starting next to the API in docker-compose, is retried with
+// backoff for up to cfg.ConnectTimeout.
+func Init(cfg config.Database) {
 	var err error
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s",
-		os.Getenv("DB_USER"),
-		os.Getenv("DB_PASSWORD"),
-		os.Getenv("DB_HOST"),
-		os.Getenv("DB_PORT"),
-		os.Getenv("DB_NAME"))
-
-	DB, err = sql.Open("mysql", dsn)
+	Current, err = DialectFor(cfg.Driver)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	if err = DB.Ping(); err != nil {
+	dsn, err := Current.DSN(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	DB, err = sql.Open(Current.DriverName(), dsn)
+	if err != nil {
 		log.Fatal(err)
 	}
-	log.Println("Database connected")
+	DB.SetMaxOpenConns(cfg.MaxOpenConns)
+	DB.SetMaxIdleConns(cfg.MaxIdleConns)
+	DB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	DB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	if err = connect(DB, cfg.ConnectTimeout); err != nil {
+		log.Fatal(err)
+	}
+	expvar.Publish("database", expvar.Func(func() interface{} { return DB.Stats() }))
+	log.Printf("Database connected (%s)", Current.Name())
+}
+
+// connect pings db until it answers, waiting 500ms after the first failure and
+// twice as long after each further one, up to 10s, until timeout has passed
+func connect(db *sql.DB, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	delay := 500 * time.Millisecond
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+		err := db.PingContext(ctx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if time.Now().Add(delay).After(deadline) {
+			return fmt.Errorf("database not reachable after %d attempts: %w", attempt, err)
+		}
+
+		log.Printf("Database not reachable (attempt %d), retrying in %s: %v", attempt, delay, err)
+		time.Sleep(delay)
+		delay = min(delay*2, 10*time.Second)
+	}
 }
@@ -0,0 +1,292 @@
+package database
+
+import (
+	"backend-api/config"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Dialect hides the differences between the supported SQL databases.
+// Queries are written with "?" placeholders and passed through Rebind.
+type Dialect interface {
+	// Name is the value of DB_DRIVER and the migrations directory of the dialect
+	Name() string
+	// DriverName is the database/sql driver to open
+	DriverName() string
+	// DSN builds the data source name from cfg
+	DSN(cfg config.Database) (string, error)
+	// Rebind rewrites "?" placeholders into the dialect's placeholder style
+	Rebind(query string) string
+	// Returning reports whether inserts return the new ID with "RETURNING id"
+	// instead of sql.Result.LastInsertId
+	Returning() bool
+	// ForUpdate is the row-locking suffix for SELECT statements inside a transaction
+	ForUpdate() string
+	// TransactionalDDL reports whether schema changes can be rolled back, so
+	// that each migration is applied atomically
+	TransactionalDDL() bool
+	// IsUniqueViolation reports whether err is a UNIQUE constraint violation
+	IsUniqueViolation(err error) bool
+	// Lock acquires the named advisory lock on conn; Unlock releases it
+	Lock(ctx context.Context, conn *sql.Conn, name string) error
+	Unlock(ctx context.Context, conn *sql.Conn, name string) error
+}
+
+// Current is the dialect selected by DB_DRIVER
+var Current Dialect
+
+// DialectFor returns the dialect for a DB_DRIVER value; empty selects MySQL
+func DialectFor(driver string) (Dialect, error) {
+	switch driver {
+	case "", "mysql":
+		return MySQL{}, nil
+	case "postgres":
+		return Postgres{}, nil
+	case "sqlite":
+		return SQLite{}, nil
+	}
+	return nil, fmt.Errorf("unknown DB_DRIVER %q", driver)
+}
+
+// InsertID runs an INSERT written with "?" placeholders and returns the ID of the new row
+func InsertID(ctx context.Context, db interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}, dialect Dialect, query string, args ...interface{}) (int64, error) {
+	if dialect.Returning() {
+		var id int64
+		err := db.QueryRowContext(ctx, dialect.Rebind(query+" RETURNING id"), args...).Scan(&id)
+		return id, err
+	}
+
+	result, err := db.ExecContext(ctx, dialect.Rebind(query), args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// MySQL is the default dialect
+type MySQL struct{}
+
+func (MySQL) Name() string       { return "mysql" }
+func (MySQL) DriverName() string { return "mysql" }
+
+// DSN scans DATETIME columns into time.Time in UTC. DB_TLS_CA_FILE registers a
+// TLS config that trusts the given CAs and requires TLS.
+func (MySQL) DSN(cfg config.Database) (string, error) {
+	dsn := mysql.NewConfig()
+	dsn.User = cfg.User
+	dsn.Passwd = cfg.Password.Reveal()
+	dsn.Net = "tcp"
+	dsn.Addr = net.JoinHostPort(cfg.Host, defaultString(cfg.Port, "3306"))
+	dsn.DBName = cfg.Name
+	dsn.ParseTime = true
+	dsn.Loc = time.UTC
+	dsn.Timeout = dialTimeout
+	dsn.TLSConfig = cfg.TLS
+
+	if cfg.TLSCAFile != "" {
+		pool, err := readCertPool(cfg.TLSCAFile)
+		if err != nil {
+			return "", err
+		}
+		if err := mysql.RegisterTLSConfig("custom", &tls.Config{RootCAs: pool, ServerName: cfg.Host}); err != nil {
+			return "", err
+		}
+		dsn.TLSConfig = "custom"
+	}
+
+	params, err := url.ParseQuery(cfg.Params)
+	if err != nil {
+		return "", err
+	}
+	if len(params) > 0 {
+		dsn.Params = map[string]string{}
+		for key := range params {
+			dsn.Params[key] = params.Get(key)
+		}
+	}
+	return dsn.FormatDSN(), nil
+}
+
+func (MySQL) Rebind(query string) string { return query }
+func (MySQL) Returning() bool            { return false }
+func (MySQL) ForUpdate() string          { return " FOR UPDATE" }
+
+// TransactionalDDL is false: MySQL commits implicitly before and after DDL
+// statements, so a migration that fails halfway stays partly applied
+func (MySQL) TransactionalDDL() bool { return false }
+
+func (MySQL) IsUniqueViolation(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062
+}
+
+func (MySQL) Lock(ctx context.Context, conn *sql.Conn, name string) error {
+	var locked sql.NullInt64
+	if err := conn.QueryRowContext(ctx, `SELECT GET_LOCK(?, ?)`, name, lockTimeoutSeconds).Scan(&locked); err != nil {
+		return err
+	}
+	if locked.Int64 != 1 {
+		return errors.New("timed out waiting for lock " + name)
+	}
+	return nil
+}
+
+func (MySQL) Unlock(ctx context.Context, conn *sql.Conn, name string) error {
+	_, err := conn.ExecContext(ctx, `SELECT RELEASE_LOCK(?)`, name)
+	return err
+}
+
+// Postgres uses $n placeholders and RETURNING id
+type Postgres struct{}
+
+func (Postgres) Name() string       { return "postgres" }
+func (Postgres) DriverName() string { return "postgres" }
+
+// DSN uses DB_SSLMODE for TLS; DB_TLS_CA_FILE becomes sslrootcert
+func (Postgres) DSN(cfg config.Database) (string, error) {
+	params, err := url.ParseQuery(cfg.Params)
+	if err != nil {
+		return "", err
+	}
+	params.Set("host", cfg.Host)
+	params.Set("port", defaultString(cfg.Port, "5432"))
+	params.Set("user", cfg.User)
+	params.Set("password", cfg.Password.Reveal())
+	params.Set("dbname", cfg.Name)
+	params.Set("sslmode", defaultString(cfg.SSLMode, "disable"))
+	params.Set("connect_timeout", strconv.Itoa(int(dialTimeout.Seconds())))
+	if cfg.TLSCAFile != "" {
+		params.Set("sslrootcert", cfg.TLSCAFile)
+	}
+
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = key + "=" + quoteDSNValue(params.Get(key))
+	}
+	return strings.Join(pairs, " "), nil
+}
+
+func (Postgres) Rebind(query string) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func (Postgres) Returning() bool        { return true }
+func (Postgres) ForUpdate() string      { return " FOR UPDATE" }
+func (Postgres) TransactionalDDL() bool { return true }
+
+func (Postgres) IsUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+func (Postgres) Lock(ctx context.Context, conn *sql.Conn, name string) error {
+	_, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock(hashtext($1))`, name)
+	return err
+}
+
+func (Postgres) Unlock(ctx context.Context, conn *sql.Conn, name string) error {
+	_, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock(hashtext($1))`, name)
+	return err
+}
+
+// SQLite stores the database in the file named by config.Database.Name and needs no server
+type SQLite struct{}
+
+func (SQLite) Name() string       { return "sqlite" }
+func (SQLite) DriverName() string { return "sqlite" }
+
+// DSN enables foreign keys (for ON DELETE CASCADE), waits on locked databases
+// instead of failing and starts write transactions immediately
+func (SQLite) DSN(cfg config.Database) (string, error) {
+	file := defaultString(cfg.Name, "backend.db")
+	return "file:" + file + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_txlock=immediate", nil
+}
+
+func (SQLite) Rebind(query string) string { return query }
+func (SQLite) Returning() bool            { return false }
+
+// ForUpdate is empty: SQLite locks the whole database for write transactions
+func (SQLite) ForUpdate() string { return "" }
+
+func (SQLite) TransactionalDDL() bool { return true }
+
+func (SQLite) IsUniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	return sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE || sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+}
+
+// Lock is a no-op: a SQLite database belongs to a single instance
+func (SQLite) Lock(ctx context.Context, conn *sql.Conn, name string) error   { return nil }
+func (SQLite) Unlock(ctx context.Context, conn *sql.Conn, name string) error { return nil }
+
+// quoteDSNValue quotes a value for a PostgreSQL key=value connection string
+func quoteDSNValue(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
+
+// dialTimeout limits establishing a single connection to a database server
+const dialTimeout = 10 * time.Second
+
+func defaultString(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// readCertPool reads the PEM encoded CA certificates in file
+func readCertPool(file string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("%s: no PEM certificates found", file)
+	}
+	return pool, nil
+}
+
+// IsUniqueViolation reports whether err is a UNIQUE constraint violation of any supported driver
+func IsUniqueViolation(err error) bool {
+	return err != nil && (MySQL{}.IsUniqueViolation(err) || Postgres{}.IsUniqueViolation(err) || SQLite{}.IsUniqueViolation(err))
+}
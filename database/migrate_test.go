@@ -0,0 +1,97 @@
+package database
+
+import (
+	"backend-api/config"
+	"context"
+	"database/sql"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+func openTestSQLite(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn, err := SQLite{}.DSN(config.Database{Name: filepath.Join(t.TempDir(), "test.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open(SQLite{}.DriverName(), dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func tableExists(t *testing.T, db *sql.DB, name string) bool {
+	t.Helper()
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, name).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	return count > 0
+}
+
+func TestMigrationsApplyOnSQLite(t *testing.T) {
+	db := openTestSQLite(t)
+	migrator, err := NewMigrator(db, SQLite{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if err := migrator.To(ctx, 0); err != nil {
+		t.Fatalf("reverting every migration: %v", err)
+	}
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("Up after reverting: %v", err)
+	}
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range statuses {
+		if !status.Applied {
+			t.Errorf("migration %d_%s is not applied", status.Version, status.Name)
+		}
+	}
+}
+
+func TestFailedMigrationIsRolledBack(t *testing.T) {
+	db := openTestSQLite(t)
+	migrator := &Migrator{db: db, dialect: SQLite{}, migrations: []Migration{
+		{Version: 1, Name: "create_a", Up: "CREATE TABLE a (id INTEGER);", Down: "DROP TABLE a;"},
+		{Version: 2, Name: "create_b", Up: "CREATE TABLE b (id INTEGER);\nINSERT INTO missing VALUES (1);", Down: "DROP TABLE b;"},
+	}}
+	ctx := context.Background()
+
+	if err := migrator.Up(ctx); err == nil {
+		t.Fatal("Up succeeded with a failing statement")
+	}
+	if !tableExists(t, db, "a") {
+		t.Error("migration 1 was rolled back with the failing migration 2")
+	}
+	if tableExists(t, db, "b") {
+		t.Error("the failing migration 2 was partly applied")
+	}
+
+	// Once fixed, the failed migration applies cleanly
+	migrator.migrations[1].Up = "CREATE TABLE b (id INTEGER);"
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("Up after fixing the migration: %v", err)
+	}
+	if !tableExists(t, db, "b") {
+		t.Error("migration 2 was not applied")
+	}
+}
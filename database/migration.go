@@ -1,38 +0,0 @@
-package database
-
-import (
-	"log"
-)
-
-func Migrate() {
-	userQuery := `CREATE TABLE IF NOT EXISTS users (
-        id INT AUTO_INCREMENT PRIMARY KEY,
-        username VARCHAR(255) NOT NULL,
-        password VARCHAR(255) NOT NULL,
-        email VARCHAR(255) NOT NULL UNIQUE,
-        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
-    )`
-
-	photoQuery := `CREATE TABLE IF NOT EXISTS photos (
-        id INT AUTO_INCREMENT PRIMARY KEY,
-        photo_url TEXT NOT NULL,
-        user_id INT,
-        is_profile BOOLEAN DEFAULT FALSE,
-        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
-        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
-    )`
-
-	_, err := DB.Exec(userQuery)
-	if err != nil {
-		log.Fatal("Error creating users table: ", err)
-	}
-
-	_, err = DB.Exec(photoQuery)
-	if err != nil {
-		log.Fatal("Error creating photos table: ", err)
-	}
-
-	log.Println("Migration completed")
-}
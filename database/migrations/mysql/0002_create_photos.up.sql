@@ -0,0 +1,13 @@
+-- profile_user_id is only set for the profile photo, so the unique key allows
+-- at most one profile photo per user
+CREATE TABLE IF NOT EXISTS photos (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    photo_url TEXT NOT NULL,
+    user_id INT,
+    is_profile BOOLEAN DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    profile_user_id INT AS (IF(is_profile, user_id, NULL)) VIRTUAL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE KEY uq_photos_profile_user (profile_user_id)
+);
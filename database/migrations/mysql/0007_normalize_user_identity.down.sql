@@ -0,0 +1,4 @@
+-- Normalized emails and renamed usernames are kept
+ALTER TABLE users DROP INDEX uq_users_username_key, DROP COLUMN username_key;
+
+ALTER TABLE users DROP INDEX uq_users_email_key, DROP COLUMN email_key;
@@ -0,0 +1,24 @@
+-- Emails are stored trimmed and lowercase and are unique regardless of case.
+-- Existing emails that only differ in case or surrounding whitespace are kept
+-- by the oldest account; the others get "<email>.duplicate-<id>.invalid",
+-- which cannot receive mail, until an admin resolves them.
+-- Usernames are unique regardless of case; accounts created before usernames
+-- were required may have '' as username. Existing duplicate usernames get
+-- their user ID appended.
+UPDATE users u JOIN users d ON LOWER(TRIM(d.email)) = LOWER(TRIM(u.email)) AND d.id < u.id
+SET u.email = CONCAT(LOWER(TRIM(u.email)), '.duplicate-', u.id, '.invalid');
+
+UPDATE users SET email = LOWER(TRIM(email)), username = TRIM(username);
+
+UPDATE users u JOIN users d ON LOWER(d.username) = LOWER(u.username) AND d.id < u.id
+SET u.username = CONCAT(u.username, '-', u.id)
+WHERE u.username <> '';
+
+-- email_key and username_key do not depend on the column collation
+ALTER TABLE users
+    ADD COLUMN email_key VARCHAR(255) AS (LOWER(email)) VIRTUAL,
+    ADD UNIQUE KEY uq_users_email_key (email_key);
+
+ALTER TABLE users
+    ADD COLUMN username_key VARCHAR(255) AS (NULLIF(LOWER(username), '')) VIRTUAL,
+    ADD UNIQUE KEY uq_users_username_key (username_key);
@@ -0,0 +1,4 @@
+-- Accounts awaiting purge are deleted for good
+DELETE FROM users WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE users DROP INDEX idx_users_deleted_at, DROP COLUMN deleted_at;
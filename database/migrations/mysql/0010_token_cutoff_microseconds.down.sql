@@ -0,0 +1 @@
+ALTER TABLE user_token_cutoffs MODIFY revoked_before DATETIME NOT NULL;
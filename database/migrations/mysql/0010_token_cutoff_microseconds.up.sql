@@ -0,0 +1,4 @@
+-- Access tokens issued before revoked_before are rejected. Access tokens carry
+-- their issue time in microseconds, so the cutoff is stored with the same
+-- precision; a token issued right after the cutoff must stay valid.
+ALTER TABLE user_token_cutoffs MODIFY revoked_before DATETIME(6) NOT NULL;
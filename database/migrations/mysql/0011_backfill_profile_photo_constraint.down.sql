@@ -0,0 +1 @@
+-- The key belongs to migration 2 and is kept; demoted profile photos stay in the gallery
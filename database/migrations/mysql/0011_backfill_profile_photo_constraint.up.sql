@@ -0,0 +1,24 @@
+-- Photos tables created before the migration engine (by database.Migrate) may
+-- lack the one-profile-photo-per-user key of migration 2, since its CREATE
+-- TABLE IF NOT EXISTS leaves existing tables alone. Keep only the newest
+-- profile photo of each user, then add the key when it is missing.
+UPDATE photos p
+JOIN (SELECT user_id, MAX(id) AS keep_id FROM photos WHERE is_profile = true GROUP BY user_id) latest
+ON p.user_id = latest.user_id
+SET p.is_profile = false
+WHERE p.is_profile = true AND p.id <> latest.keep_id;
+
+SET @add_profile_key = (
+    SELECT COUNT(*) = 0 FROM information_schema.COLUMNS
+    WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'photos' AND COLUMN_NAME = 'profile_user_id'
+);
+
+SET @ddl = IF(@add_profile_key,
+    'ALTER TABLE photos ADD COLUMN profile_user_id INT AS (IF(is_profile, user_id, NULL)) VIRTUAL, ADD UNIQUE KEY uq_photos_profile_user (profile_user_id)',
+    'DO 0');
+
+PREPARE backfill_profile_key FROM @ddl;
+
+EXECUTE backfill_profile_key;
+
+DEALLOCATE PREPARE backfill_profile_key;
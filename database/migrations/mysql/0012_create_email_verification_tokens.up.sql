@@ -0,0 +1,13 @@
+-- Email verification tokens are opaque and stored as SHA-256 hashes, like
+-- password reset tokens. They replace signed verification links, which were
+-- signed with the access token key. Links sent before this migration stop
+-- working; users can request a new one.
+CREATE TABLE email_verification_tokens (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    expires_at DATETIME NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
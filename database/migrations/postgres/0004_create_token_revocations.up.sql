@@ -0,0 +1,17 @@
+-- Access tokens revoked individually (logout), by their jti claim.
+-- Rows are kept without a foreign key so revocations outlive deleted users.
+CREATE TABLE revoked_tokens (
+    jti CHAR(32) PRIMARY KEY,
+    user_id INT NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_revoked_tokens_expires_at ON revoked_tokens (expires_at);
+
+-- Access tokens of a user issued at or before revoked_before are rejected
+-- ("log out all sessions", password change, account deletion).
+CREATE TABLE user_token_cutoffs (
+    user_id INT PRIMARY KEY,
+    revoked_before TIMESTAMP NOT NULL
+);
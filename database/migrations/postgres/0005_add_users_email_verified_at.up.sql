@@ -0,0 +1,5 @@
+-- Accounts registered from now on start unverified; existing accounts are
+-- treated as verified so they are not locked out.
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP NULL DEFAULT NULL;
+
+UPDATE users SET email_verified_at = CURRENT_TIMESTAMP;
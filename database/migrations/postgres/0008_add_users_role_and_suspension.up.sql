@@ -0,0 +1,4 @@
+-- Every existing account is a regular user; admins are promoted explicitly.
+ALTER TABLE users ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'user';
+
+ALTER TABLE users ADD COLUMN suspended_at TIMESTAMP NULL DEFAULT NULL;
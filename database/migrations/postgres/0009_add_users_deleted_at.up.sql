@@ -0,0 +1,5 @@
+-- Deleted accounts keep their row until the purge job removes them after the
+-- restore window; the index serves that job.
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMP NULL DEFAULT NULL;
+
+CREATE INDEX idx_users_deleted_at ON users (deleted_at);
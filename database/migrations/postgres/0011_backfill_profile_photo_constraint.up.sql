@@ -0,0 +1,8 @@
+-- Keep only the newest profile photo of each user, then make sure the partial
+-- index of migration 2 that allows one profile photo per user exists.
+UPDATE photos SET is_profile = false
+WHERE is_profile AND EXISTS (
+    SELECT 1 FROM photos p WHERE p.user_id = photos.user_id AND p.is_profile AND p.id > photos.id
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_photos_profile_user ON photos (user_id) WHERE is_profile;
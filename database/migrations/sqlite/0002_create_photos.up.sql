@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS photos (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    photo_url TEXT NOT NULL,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    is_profile BOOLEAN DEFAULT FALSE,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- At most one profile photo per user
+CREATE UNIQUE INDEX IF NOT EXISTS uq_photos_profile_user ON photos (user_id) WHERE is_profile;
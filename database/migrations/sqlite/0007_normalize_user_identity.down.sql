@@ -0,0 +1,4 @@
+-- Normalized emails and renamed usernames are kept
+DROP INDEX IF EXISTS uq_users_username_lower;
+
+DROP INDEX IF EXISTS uq_users_email_lower;
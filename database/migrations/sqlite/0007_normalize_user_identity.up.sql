@@ -0,0 +1,22 @@
+-- Emails are stored trimmed and lowercase and are unique regardless of case.
+-- Existing emails that only differ in case or surrounding whitespace are kept
+-- by the oldest account; the others get "<email>.duplicate-<id>.invalid",
+-- which cannot receive mail, until an admin resolves them.
+-- Usernames are unique regardless of case; accounts created before usernames
+-- were required may have '' as username. Existing duplicate usernames get
+-- their user ID appended.
+UPDATE users SET email = LOWER(TRIM(email)) || '.duplicate-' || id || '.invalid'
+WHERE EXISTS (
+    SELECT 1 FROM users d WHERE LOWER(TRIM(d.email)) = LOWER(TRIM(users.email)) AND d.id < users.id
+);
+
+UPDATE users SET email = LOWER(TRIM(email)), username = TRIM(username);
+
+UPDATE users SET username = username || '-' || id
+WHERE username <> '' AND EXISTS (
+    SELECT 1 FROM users d WHERE LOWER(d.username) = LOWER(users.username) AND d.id < users.id
+);
+
+CREATE UNIQUE INDEX uq_users_email_lower ON users (LOWER(email));
+
+CREATE UNIQUE INDEX uq_users_username_lower ON users (LOWER(username)) WHERE username <> '';
@@ -0,0 +1,6 @@
+-- Accounts awaiting purge are deleted for good
+DELETE FROM users WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_users_deleted_at;
+
+ALTER TABLE users DROP COLUMN deleted_at;
@@ -0,0 +1,3 @@
+-- Access tokens issued before revoked_before are rejected. Access tokens carry
+-- their issue time in microseconds; revoked_before already stores microseconds
+-- here, so only MySQL needs a schema change.
@@ -1,23 +1,22 @@
 package helpers
 
 import (
-	"backend-api/database"
+	"backend-api/app"
+	"backend-api/redact"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
-	"os"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// RespondWithError memberikan respon dengan pesan error dalam format JSON
-func RespondWithError(w http.ResponseWriter, code int, message string) {
-	RespondWithJSON(w, code, map[string]string{"error": message})
-}
-
 // RespondWithJSON memberikan respon dengan payload dalam format JSON
 func RespondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	response, _ := json.Marshal(payload)
@@ -27,33 +26,51 @@ func RespondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 }
 
 // HashPassword membuat hash dari password dalam plain-text
-func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
-	return string(bytes), err
+func HashPassword(password redact.Secret) (redact.Secret, error) {
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password.Reveal()), 14)
+	return redact.Secret(bytes), err
 }
 
 // CheckPasswordHash memeriksa apakah hashed password cocok dengan plain-text password
-func CheckPasswordHash(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+func CheckPasswordHash(password, hash redact.Secret) bool {
+	err := bcrypt.CompareHashAndPassword([]byte(hash.Reveal()), []byte(password.Reveal()))
 	return err == nil
 }
 
-// GenerateJWT menghasilkan token JWT untuk seorang pengguna
-func GenerateJWT(userID uint) (string, error) {
-	// Dapatkan JWT secret dari environment variable
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		return "", errors.New("variabel lingkungan JWT_SECRET tidak diatur")
+// GenerateJWT menghasilkan token akses JWT untuk seorang pengguna yang berlaku selama ttl
+func GenerateJWT(user app.User, ttl time.Duration) (string, error) {
+	// jti mengidentifikasi token agar dapat dicabut satu per satu
+	jti, err := RandomID()
+	if err != nil {
+		return "", err
 	}
 
-	// Buat token JWT baru dengan userID sebagai claim
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": userID,
-		"exp":     time.Now().Add(time.Hour * 72).Unix(),
+	// Buat token JWT baru dengan ID pengguna sebagai claim. iat memakai pecahan
+	// detik (mikrodetik) agar token yang diterbitkan sesaat setelah semua token
+	// pengguna dicabut tidak ikut tercabut.
+	now := time.Now()
+	return signJWT(jwt.MapClaims{
+		"user_id":        user.ID,
+		"email_verified": user.EmailVerifiedAt != nil,
+		"role":           string(user.Role),
+		"jti":            jti,
+		"iat":            float64(now.UnixMicro()) / 1e6,
+		"exp":            now.Add(ttl).Unix(),
 	})
+}
+
+// signJWT menandatangani claims dengan kunci penandatangan aktif
+func signJWT(claims jwt.MapClaims) (string, error) {
+	if jwtKeys == nil {
+		return "", errors.New("kunci JWT belum dimuat")
+	}
+
+	token := jwt.NewWithClaims(jwtKeys.signingMethod, claims)
+	if jwtKeys.signingKID != "" {
+		token.Header["kid"] = jwtKeys.signingKID
+	}
 
-	// Tandatangani token dengan kunci rahasia
-	tokenString, err := token.SignedString([]byte(secret))
+	tokenString, err := token.SignedString(jwtKeys.signingKey)
 	if err != nil {
 		return "", fmt.Errorf("kesalahan menghasilkan token JWT: %v", err)
 	}
@@ -61,46 +78,50 @@ func GenerateJWT(userID uint) (string, error) {
 	return tokenString, nil
 }
 
+// GenerateOpaqueToken menghasilkan token acak yang aman untuk URL beserta hash SHA-256-nya.
+// Hanya hash yang disimpan di database.
+func GenerateOpaqueToken() (token string, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, HashToken(token), nil
+}
+
+// HashToken mengembalikan hash SHA-256 (hex) dari sebuah token opaque
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RandomID menghasilkan ID acak 32 karakter hex
+func RandomID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
 // ValidateJWT memvalidasi token JWT
 func ValidateJWT(tokenString string) (*jwt.Token, error) {
-	secret := os.Getenv("JWT_SECRET")
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("metode tanda tangan tidak terduga: %v", token.Header["alg"])
-		}
-		return []byte(secret), nil
-	})
+	token, err := jwt.Parse(tokenString, verificationKey)
 
 	if err != nil {
 		return nil, err
 	}
 
 	// Periksa apakah token valid dan berisi klaim user_id
-	if _, ok := token.Claims.(jwt.MapClaims); !ok || !token.Valid {
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
 		return nil, errors.New("token tidak valid")
 	}
 
-	return token, nil
-}
-
-// DeletePhotoFileFromDB menghapus foto dari database MySQL berdasarkan ID foto.
-func DeletePhotoFileFromDB(photoID uint) error {
-	// Buatlah koneksi ke database Anda (database.DB)
-
-	// Lakukan query untuk mendapatkan informasi foto berdasarkan ID
-	query := "SELECT photo_url FROM photos WHERE id = ?"
-	var photoURL string
-	err := database.DB.QueryRow(query, photoID).Scan(&photoURL)
-	if err != nil {
-		return err
-	}
-
-	// Hapus foto dari database
-	deleteQuery := "DELETE FROM photos WHERE id = ?"
-	_, err = database.DB.Exec(deleteQuery, photoID)
-	if err != nil {
-		return err
+	// Token verifikasi email lama masih berupa JWT dengan claim purpose; tolak sebagai token akses
+	if _, ok := claims["purpose"]; ok {
+		return nil, errors.New("token tidak valid")
 	}
 
-	return nil
+	return token, nil
 }
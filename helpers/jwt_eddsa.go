@@ -0,0 +1,52 @@
+package helpers
+
+import (
+	"crypto/ed25519"
+	"errors"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// SigningMethodEdDSA menandatangani token dengan Ed25519 (alg "EdDSA", RFC 8037).
+// jwt-go v3 tidak menyediakan metode ini sehingga didaftarkan di sini.
+type SigningMethodEdDSA struct{}
+
+// EdDSA adalah instance SigningMethodEdDSA yang terdaftar di jwt-go
+var EdDSA = &SigningMethodEdDSA{}
+
+func init() {
+	jwt.RegisterSigningMethod(EdDSA.Alg(), func() jwt.SigningMethod {
+		return EdDSA
+	})
+}
+
+// Alg mengembalikan nama algoritma untuk header "alg"
+func (m *SigningMethodEdDSA) Alg() string {
+	return "EdDSA"
+}
+
+// Verify memeriksa tanda tangan dengan kunci ed25519.PublicKey
+func (m *SigningMethodEdDSA) Verify(signingString, signature string, key interface{}) error {
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return jwt.ErrInvalidKeyType
+	}
+
+	sig, err := jwt.DecodeSegment(signature)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(publicKey, []byte(signingString), sig) {
+		return errors.New("tanda tangan EdDSA tidak valid")
+	}
+	return nil
+}
+
+// Sign menandatangani signingString dengan kunci ed25519.PrivateKey
+func (m *SigningMethodEdDSA) Sign(signingString string, key interface{}) (string, error) {
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return "", jwt.ErrInvalidKeyType
+	}
+	return jwt.EncodeSegment(ed25519.Sign(privateKey, []byte(signingString))), nil
+}
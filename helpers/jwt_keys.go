@@ -0,0 +1,254 @@
+package helpers
+
+import (
+	"backend-api/config"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// jwtKey adalah kunci asimetris yang dapat memverifikasi token dengan "kid" tertentu
+type jwtKey struct {
+	kid       string
+	method    jwt.SigningMethod
+	publicKey crypto.PublicKey
+}
+
+// jwtKeySet menyimpan kunci penandatangan aktif dan semua kunci verifikasi
+type jwtKeySet struct {
+	// signingMethod dan signingKey dipakai GenerateJWT; signingKID kosong untuk HS256
+	signingMethod jwt.SigningMethod
+	signingKey    interface{}
+	signingKID    string
+	// hmacSecret diisi dari JWT_SECRET; token HS256 hanya diterima jika diatur
+	hmacSecret []byte
+	// publicKeys berisi kunci aktif dan kunci lama yang masih diterima, berdasarkan kid
+	publicKeys map[string]jwtKey
+}
+
+var jwtKeys *jwtKeySet
+
+// InitJWTKeys memuat kunci JWT sesuai cfg:
+//
+//	SigningAlg           HS256 (default), RS256 atau EdDSA
+//	Secret               secret HS256; jika diatur, token HS256 tetap diterima
+//	PrivateKeyFile       kunci privat PEM untuk RS256/EdDSA
+//	KeyID                kid kunci aktif (default: thumbprint RFC 7638)
+//	VerificationKeysDir  direktori <kid>.pem berisi kunci publik tambahan untuk rotasi
+func InitJWTKeys(cfg config.JWT) {
+	keys, err := loadJWTKeys(cfg)
+	if err != nil {
+		log.Fatal("Error loading JWT keys: ", err)
+	}
+	jwtKeys = keys
+}
+
+func loadJWTKeys(cfg config.JWT) (*jwtKeySet, error) {
+	keys := &jwtKeySet{publicKeys: map[string]jwtKey{}}
+	if cfg.Secret != "" {
+		keys.hmacSecret = []byte(cfg.Secret.Reveal())
+	}
+
+	alg := cfg.SigningAlg
+	switch alg {
+	case "", "HS256":
+		if keys.hmacSecret == nil {
+			return nil, errors.New("variabel lingkungan JWT_SECRET tidak diatur")
+		}
+		keys.signingMethod = jwt.SigningMethodHS256
+		keys.signingKey = keys.hmacSecret
+	case "RS256", "EdDSA":
+		privateKey, err := readPrivateKey(cfg.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		key, err := newJWTKey(cfg.KeyID, privateKey.Public())
+		if err != nil {
+			return nil, err
+		}
+		if key.method.Alg() != alg {
+			return nil, fmt.Errorf("JWT_PRIVATE_KEY_FILE bukan kunci %s", alg)
+		}
+		keys.signingMethod = key.method
+		keys.signingKey = privateKey
+		keys.signingKID = key.kid
+		keys.publicKeys[key.kid] = key
+	default:
+		return nil, fmt.Errorf("JWT_SIGNING_ALG %q tidak didukung", alg)
+	}
+
+	if dir := cfg.VerificationKeysDir; dir != "" {
+		files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			publicKey, err := readPublicKey(file)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", file, err)
+			}
+			key, err := newJWTKey(strings.TrimSuffix(filepath.Base(file), ".pem"), publicKey)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", file, err)
+			}
+			if _, exists := keys.publicKeys[key.kid]; !exists {
+				keys.publicKeys[key.kid] = key
+			}
+		}
+	}
+
+	return keys, nil
+}
+
+// newJWTKey menentukan algoritma dari tipe kunci; kid kosong diganti thumbprint kunci
+func newJWTKey(kid string, publicKey crypto.PublicKey) (jwtKey, error) {
+	key := jwtKey{kid: kid, publicKey: publicKey}
+	switch publicKey.(type) {
+	case *rsa.PublicKey:
+		key.method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		key.method = EdDSA
+	default:
+		return key, fmt.Errorf("tipe kunci %T tidak didukung", publicKey)
+	}
+
+	if key.kid == "" {
+		key.kid = thumbprint(key.jwk())
+	}
+	return key, nil
+}
+
+// jwk mengembalikan representasi JSON Web Key dari kunci publik
+func (k jwtKey) jwk() map[string]string {
+	switch publicKey := k.publicKey.(type) {
+	case *rsa.PublicKey:
+		return map[string]string{
+			"kty": "RSA",
+			"n":   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+		}
+	case ed25519.PublicKey:
+		return map[string]string{
+			"kty": "OKP",
+			"crv": "Ed25519",
+			"x":   base64.RawURLEncoding.EncodeToString(publicKey),
+		}
+	}
+	return nil
+}
+
+// thumbprint menghitung JWK thumbprint (RFC 7638) dari anggota wajib sebuah JWK
+func thumbprint(jwk map[string]string) string {
+	// json.Marshal mengurutkan key map secara leksikografis, sesuai RFC 7638
+	b, _ := json.Marshal(jwk)
+	sum := sha256.Sum256(b)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// JWKS mengembalikan kunci publik verifikasi dalam format JSON Web Key Set
+func JWKS() map[string]interface{} {
+	keys := []map[string]string{}
+	if jwtKeys != nil {
+		for _, key := range jwtKeys.publicKeys {
+			jwk := key.jwk()
+			jwk["kid"] = key.kid
+			jwk["alg"] = key.method.Alg()
+			jwk["use"] = "sig"
+			keys = append(keys, jwk)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i]["kid"] < keys[j]["kid"] })
+	return map[string]interface{}{"keys": keys}
+}
+
+// verificationKey adalah jwt.Keyfunc yang memilih kunci berdasarkan header "kid" dan "alg"
+func verificationKey(token *jwt.Token) (interface{}, error) {
+	if jwtKeys == nil {
+		return nil, errors.New("kunci JWT belum dimuat")
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		// Token tanpa kid hanya diterima sebagai HS256 jika JWT_SECRET diatur
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok || jwtKeys.hmacSecret == nil {
+			return nil, fmt.Errorf("metode tanda tangan tidak terduga: %v", token.Header["alg"])
+		}
+		return jwtKeys.hmacSecret, nil
+	}
+
+	key, ok := jwtKeys.publicKeys[kid]
+	if !ok {
+		return nil, fmt.Errorf("kid tidak dikenal: %s", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("metode tanda tangan tidak terduga: %v", token.Header["alg"])
+	}
+	return key.publicKey, nil
+}
+
+// readPrivateKey membaca kunci privat RSA (PKCS#1/PKCS#8) atau Ed25519 (PKCS#8) dari berkas PEM
+func readPrivateKey(file string) (crypto.Signer, error) {
+	if file == "" {
+		return nil, errors.New("variabel lingkungan JWT_PRIVATE_KEY_FILE tidak diatur")
+	}
+	block, err := readPEM(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: kunci privat tidak valid: %v", file, err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%s: tipe kunci %T tidak didukung", file, key)
+	}
+	return signer, nil
+}
+
+// readPublicKey membaca kunci publik PKIX dari berkas PEM; kunci privat juga diterima
+func readPublicKey(file string) (crypto.PublicKey, error) {
+	block, err := readPEM(file)
+	if err != nil {
+		return nil, err
+	}
+	if strings.Contains(block.Type, "PRIVATE KEY") {
+		signer, err := readPrivateKey(file)
+		if err != nil {
+			return nil, err
+		}
+		return signer.Public(), nil
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+func readPEM(file string) (*pem.Block, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: bukan berkas PEM", file)
+	}
+	return block, nil
+}
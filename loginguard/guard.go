@@ -0,0 +1,150 @@
+// Package loginguard protects password checks against brute force. Failed
+// attempts are counted per account and per client IP; once a key has failed
+// more often than its policy allows, it is locked out for a delay that doubles
+// with every further failure. Each attempt is counted before the password is
+// compared, so guesses sent in parallel cannot all pass the lockout check.
+package loginguard
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"math"
+	"time"
+)
+
+// Policy limits the failed attempts of one kind of key
+type Policy struct {
+	// Threshold is the number of failures allowed before lockouts start
+	Threshold int
+	// BaseDelay is the lockout after the first failure over Threshold; each
+	// further failure doubles it, up to MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Window is how long failures are remembered after the last one. It must be
+	// longer than MaxDelay.
+	Window time.Duration
+}
+
+// Default policies. The IP policy is looser because several users may share an
+// address behind a NAT.
+var (
+	DefaultAccountPolicy = Policy{Threshold: 5, BaseDelay: 30 * time.Second, MaxDelay: 15 * time.Minute, Window: time.Hour}
+	DefaultIPPolicy      = Policy{Threshold: 20, BaseDelay: 30 * time.Second, MaxDelay: time.Hour, Window: 2 * time.Hour}
+)
+
+// lockout returns how long a key is locked after its latest failure
+func (p Policy) lockout(failures int) time.Duration {
+	over := failures - p.Threshold
+	if over <= 0 {
+		return 0
+	}
+	// 2^over overflows long before it matters, so cap the exponent
+	delay := time.Duration(float64(p.BaseDelay) * math.Pow(2, math.Min(float64(over-1), 32)))
+	if delay > p.MaxDelay || delay <= 0 {
+		return p.MaxDelay
+	}
+	return delay
+}
+
+// wait returns how long a key with attempts is still locked at now
+func (p Policy) wait(attempts Attempts, now time.Time) time.Duration {
+	return attempts.LastFailure.Add(p.lockout(attempts.Failures)).Sub(now)
+}
+
+// Guard tracks login attempts in a Store
+type Guard struct {
+	store   Store
+	Account Policy
+	IP      Policy
+}
+
+// New returns a Guard with the default policies
+func New(store Store) *Guard {
+	return &Guard{store: store, Account: DefaultAccountPolicy, IP: DefaultIPPolicy}
+}
+
+// Attempt is a password check reserved by Reserve. It stays counted as a
+// failure unless Succeed is called.
+type Attempt struct {
+	guard        *Guard
+	account      string
+	reservations []reservation
+}
+
+type reservation struct {
+	key      string
+	at       time.Time
+	previous Attempts
+}
+
+// Reserve counts a password check of account from ip as a failed attempt
+// before the password is compared. When the account or the IP is locked out,
+// nothing is counted and Reserve returns how long the caller has to wait.
+// Store errors are logged and do not block logins.
+func (g *Guard) Reserve(ctx context.Context, account, ip string) (*Attempt, time.Duration) {
+	now := time.Now()
+	attempt := &Attempt{guard: g, account: account}
+	for _, key := range g.keys(account, ip) {
+		previous, wait, err := g.store.Reserve(ctx, key.name, now, key.policy)
+		if err != nil {
+			log.Printf("Error recording login attempt: %v", err)
+			continue
+		}
+		if wait > 0 {
+			attempt.release(ctx, "")
+			return nil, wait
+		}
+		attempt.reservations = append(attempt.reservations, reservation{key.name, now, previous})
+	}
+	return attempt, 0
+}
+
+// Succeed records that the password matched: the failures of the account are
+// cleared and the attempt is no longer counted against the IP. Earlier failures
+// of the IP are kept, so a client cannot reset its own counter by logging into
+// an account it controls.
+func (a *Attempt) Succeed(ctx context.Context) {
+	account := accountKey(a.account)
+	if err := a.guard.store.Reset(ctx, account); err != nil {
+		log.Printf("Error resetting login attempts: %v", err)
+	}
+	a.release(ctx, account)
+}
+
+// Cancel undoes the attempt when the password could not be compared, e.g.
+// because the database failed
+func (a *Attempt) Cancel(ctx context.Context) {
+	a.release(ctx, "")
+}
+
+// release undoes the reservations of the attempt, except the one of key except
+func (a *Attempt) release(ctx context.Context, except string) {
+	for _, r := range a.reservations {
+		if r.key == except {
+			continue
+		}
+		if err := a.guard.store.Release(ctx, r.key, r.at, r.previous); err != nil {
+			log.Printf("Error releasing login attempt: %v", err)
+		}
+	}
+}
+
+type guardKey struct {
+	name   string
+	policy Policy
+}
+
+func (g *Guard) keys(account, ip string) []guardKey {
+	return []guardKey{
+		{accountKey(account), g.Account},
+		{"login:ip:" + ip, g.IP},
+	}
+}
+
+// accountKey hashes the account so the store does not hold email addresses
+func accountKey(account string) string {
+	sum := sha256.Sum256([]byte(account))
+	return "login:account:" + hex.EncodeToString(sum[:])
+}
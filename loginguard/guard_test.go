@@ -0,0 +1,90 @@
+package loginguard
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testPolicy = Policy{Threshold: 3, BaseDelay: time.Minute, MaxDelay: time.Hour, Window: 2 * time.Hour}
+
+func newTestGuard() (*Guard, *MemoryStore) {
+	store := NewMemoryStore()
+	return &Guard{store: store, Account: testPolicy, IP: Policy{Threshold: 10, BaseDelay: time.Minute, MaxDelay: time.Hour, Window: 2 * time.Hour}}, store
+}
+
+func TestParallelAttemptsStopAtThreshold(t *testing.T) {
+	guard, _ := newTestGuard()
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Every attempt fails, so nobody calls Succeed
+			if _, wait := guard.Reserve(ctx, "alice@example.com", "10.0.0.1"); wait == 0 {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Threshold failures are free and the next one starts the lockout
+	if want := testPolicy.Threshold + 1; allowed != want {
+		t.Errorf("%d parallel attempts passed, want %d", allowed, want)
+	}
+}
+
+func TestSucceedKeepsEarlierFailuresOfIP(t *testing.T) {
+	guard, store := newTestGuard()
+	ctx := context.Background()
+	ip := "10.0.0.1"
+
+	for i := 0; i < 2; i++ {
+		if _, wait := guard.Reserve(ctx, "mallory@example.com", ip); wait > 0 {
+			t.Fatalf("attempt %d locked for %s", i+1, wait)
+		}
+	}
+	before, _ := store.Get(ctx, "login:ip:"+ip)
+
+	attempt, wait := guard.Reserve(ctx, "alice@example.com", ip)
+	if wait > 0 {
+		t.Fatalf("attempt locked for %s", wait)
+	}
+	attempt.Succeed(ctx)
+
+	after, _ := store.Get(ctx, "login:ip:"+ip)
+	if after != before {
+		t.Errorf("IP attempts after a successful login = %+v, want %+v", after, before)
+	}
+	if account, _ := store.Get(ctx, accountKey("alice@example.com")); account.Failures != 0 {
+		t.Errorf("account failures after a successful login = %d, want 0", account.Failures)
+	}
+}
+
+func TestLockedAttemptIsNotCounted(t *testing.T) {
+	guard, store := newTestGuard()
+	ctx := context.Background()
+
+	for i := 0; i <= testPolicy.Threshold; i++ {
+		guard.Reserve(ctx, "alice@example.com", "10.0.0.1")
+	}
+	locked, _ := store.Get(ctx, accountKey("alice@example.com"))
+	ip, _ := store.Get(ctx, "login:ip:10.0.0.2")
+
+	if _, wait := guard.Reserve(ctx, "alice@example.com", "10.0.0.2"); wait <= 0 {
+		t.Fatal("account is not locked after the threshold")
+	}
+	if got, _ := store.Get(ctx, accountKey("alice@example.com")); got != locked {
+		t.Errorf("account attempts = %+v after a locked attempt, want %+v", got, locked)
+	}
+	if got, _ := store.Get(ctx, "login:ip:10.0.0.2"); got != ip {
+		t.Errorf("IP attempts = %+v after a locked attempt, want %+v", got, ip)
+	}
+}
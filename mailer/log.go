@@ -0,0 +1,62 @@
+package mailer
+
+import (
+	"backend-api/redact"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LogMailer writes emails to the application log instead of sending them.
+// It is meant for local development only: the logged bodies contain working
+// verification and password reset links.
+type LogMailer struct {
+	From string
+}
+
+// Send logs msg
+func (m LogMailer) Send(ctx context.Context, msg Message) error {
+	log.Printf("Mail to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// FileMailer writes every email as an .eml file in Dir instead of sending it.
+// It is meant for local development and tests.
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+// NewFileMailer creates dir if needed and returns a FileMailer
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileMailer{Dir: dir, From: from}, nil
+}
+
+// Send writes msg to a new file named after the current time
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	data, err := format(m.From, msg)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(m.Dir, fmt.Sprintf("%d-*.eml", time.Now().UnixNano()))
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	log.Printf("Mail to %s written to %s", redact.Email(msg.To), filepath.Base(f.Name()))
+	return nil
+}
@@ -1,42 +1,160 @@
 package main
 
 import (
+	"backend-api/accounts"
+	"backend-api/config"
+	"backend-api/controllers"
 	"backend-api/database"
+	"backend-api/helpers"
+	"backend-api/loginguard"
+	"backend-api/mailer"
+	"backend-api/models"
+	"context"
+	"expvar"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"backend-api/router"
-
-	"github.com/gorilla/mux"
-	"github.com/joho/godotenv"
+	"backend-api/storage"
 )
 
-func init() {
-	// Load environment variables from .env file
-	err := godotenv.Load()
+func main() {
+	// Run "migrate ..." subcommands instead of the server; they only need the
+	// database settings
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		dbConfig, err := config.LoadDatabase()
+		if err != nil {
+			log.Fatalf("Invalid configuration:\n%v", err)
+		}
+		database.Init(dbConfig)
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// Load and validate the configuration before touching any subsystem
+	cfg, err := config.Load()
 	if err != nil {
-		log.Fatal("Error loading .env file")
+		log.Fatalf("Invalid configuration:\n%v", err)
 	}
 
-	// Initialize and migrate the database
-	database.Init()
+	// Initialize the database connection
+	database.Init(cfg.Database)
+
+	// Apply pending migrations before serving requests
 	database.Migrate()
-}
 
-func main() {
-	// Create a new router
-	r := mux.NewRouter()
+	// Load the keys used to sign and verify access tokens
+	helpers.InitJWTKeys(cfg.JWT)
+
+	// Initialize the storage backend for uploaded photos
+	store, err := storage.New(cfg.Storage, cfg.Server.PublicURL)
+	if err != nil {
+		log.Fatal("Error initializing storage: ", err)
+	}
+
+	// Initialize the mailer for verification emails
+	mail, err := mailer.New(cfg.Mail)
+	if err != nil {
+		log.Fatal("Error initializing mailer: ", err)
+	}
+
+	// Track failed logins in memory or, for several instances, in Redis
+	loginStore, err := loginguard.NewStore(cfg.LoginGuard)
+	if err != nil {
+		log.Fatal("Error initializing login guard: ", err)
+	}
+
+	// Wire the handlers to the SQL repositories
+	server := controllers.NewServer(
+		cfg,
+		models.NewSQLUserRepository(database.DB, database.Current),
+		models.NewSQLPhotoRepository(database.DB, database.Current),
+		models.NewSQLTokenRepository(database.DB, database.Current),
+		store,
+		mail,
+	)
+	server.LoginGuard = loginguard.New(loginStore)
+
+	// Stop on SIGINT/SIGTERM; a second signal kills the process immediately
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Load revoked tokens and keep the cache in sync with the database
+	revocationsDone := server.Revocations.Start(ctx, 30*time.Second)
+
+	// Permanently delete accounts whose restore window has passed
+	purgerDone := accounts.NewPurger(server.Users, server.Photos, store, cfg.Accounts.RestoreWindow).Start(ctx, time.Hour)
 
 	// Set up routes using router from the router package
-	r = router.NewRouter()
+	r := router.NewRouter(server)
 
 	// Start the server
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "3000" // Default port if not specified
+	port := cfg.Server.Port
+	srv := &http.Server{
+		Addr:              ":" + port,
+		Handler:           r,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+
+	serveErr := make(chan error, 2)
+	go func() {
+		log.Printf("Server running on port %s", port)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	// Expose runtime and connection pool statistics to monitoring on a
+	// separate listener that is not reachable through the public port
+	var debugSrv *http.Server
+	if cfg.Server.DebugAddr != "" {
+		debug := http.NewServeMux()
+		debug.Handle("/debug/vars", expvar.Handler())
+		debugSrv = &http.Server{Addr: cfg.Server.DebugAddr, Handler: debug, ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout}
+		go func() {
+			log.Printf("Debug server running on %s", cfg.Server.DebugAddr)
+			serveErr <- debugSrv.ListenAndServe()
+		}()
+	}
+
+	select {
+	case err := <-serveErr:
+		log.Fatal(err)
+	case <-ctx.Done():
+	}
+	stop()
+
+	// Drain in-flight requests, then the background work, within one deadline
+	log.Printf("Shutting down, waiting up to %s for in-flight requests", cfg.Server.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Error shutting down HTTP server: %v", err)
+	}
+	if debugSrv != nil {
+		debugSrv.Close()
+	}
+	if err := server.Wait(shutdownCtx); err != nil {
+		log.Printf("Error waiting for background work: %v", err)
+	}
+	for _, done := range []<-chan struct{}{revocationsDone, purgerDone} {
+		select {
+		case <-done:
+		case <-shutdownCtx.Done():
+			log.Printf("Error stopping background workers: %v", shutdownCtx.Err())
+		}
 	}
 
-	log.Printf("Server running on port %s", port)
-	log.Fatal(http.ListenAndServe(":"+port, r))
+	if err := database.DB.Close(); err != nil {
+		log.Printf("Error closing database: %v", err)
+	}
+	log.Println("Server stopped")
 }
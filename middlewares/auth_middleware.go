@@ -1,13 +1,15 @@
 package middlewares
 
 import (
+	"backend-api/apierror"
+	"backend-api/app"
 	"backend-api/helpers"
 	"context"
-	"fmt"
 	"log"
+	"math"
 	"net/http"
-	"os"
 	"strings"
+	"time"
 
 	"github.com/dgrijalva/jwt-go"
 )
@@ -15,59 +17,126 @@ import (
 // Define the key type for user ID in context
 type key int
 
-const userContextKey key = 0
+const (
+	userContextKey key = iota
+	tokenContextKey
+	requestIDContextKey
+	clientIPContextKey
+)
+
+// TokenInfo identifies the access token that authenticated a request
+type TokenInfo struct {
+	ID        string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+	// EmailVerified reports whether the user's email was verified when the token was issued
+	EmailVerified bool
+	// Role is the user's role when the token was issued
+	Role app.Role
+}
+
+// RevocationChecker reports whether an access token has been revoked
+type RevocationChecker interface {
+	IsRevoked(jti string, userID uint, issuedAt time.Time) bool
+}
+
+// StatusChecker returns the current status of a user
+type StatusChecker interface {
+	UserStatus(userID uint) (app.UserStatus, error)
+}
 
-// JWTAuth middleware validates JWT tokens and extracts user ID from claims
-func JWTAuth(next http.Handler) http.Handler {
+// JWTAuth returns a middleware that validates JWT tokens, rejects tokens revoked
+// in revoked or belonging to users that accounts reports as suspended or deleted,
+// and extracts user ID from claims
+func JWTAuth(revoked RevocationChecker, accounts StatusChecker) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return jwtAuthHandler(revoked, accounts, next)
+	}
+}
+
+func jwtAuthHandler(revoked RevocationChecker, accounts StatusChecker, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		tokenString := r.Header.Get("Authorization")
 		if tokenString == "" {
 			log.Println("Missing auth token")
-			helpers.RespondWithError(w, http.StatusUnauthorized, "Missing auth token")
+			apierror.Write(w, r, apierror.ErrAuthTokenMissing)
 			return
 		}
 
-		// Remove "Bearer " prefix from token string
+		// Remove "Bearer " prefix from token string; the token itself is never logged
 		tokenString = strings.TrimPrefix(tokenString, "Bearer ")
-		log.Printf("Token after trimming prefix: %s", tokenString)
-
-		// Validate JWT token
-		secret := os.Getenv("JWT_SECRET")
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(secret), nil
-		})
-
-		if err != nil || !token.Valid {
+
+		// Validate JWT token against the active and rotated verification keys
+		token, err := helpers.ValidateJWT(tokenString)
+		if err != nil {
 			log.Printf("Token validation error: %v", err)
-			helpers.RespondWithError(w, http.StatusUnauthorized, "Invalid token")
+			apierror.Write(w, r, apierror.ErrAuthTokenInvalid)
 			return
 		}
 
 		// Extract user ID from claims
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			log.Println("Invalid token claims")
-			helpers.RespondWithError(w, http.StatusUnauthorized, "Invalid token")
-			return
-		}
+		claims := token.Claims.(jwt.MapClaims)
 
 		// Convert user ID claim to uint
 		userIDFloat, ok := claims["user_id"].(float64)
 		if !ok {
 			log.Println("Invalid user_id type in token claims")
-			helpers.RespondWithError(w, http.StatusUnauthorized, "Invalid token")
+			apierror.Write(w, r, apierror.ErrAuthTokenInvalid)
 			return
 		}
 
 		userID := uint(userIDFloat)
 
+		// Reject tokens that have been revoked by logout or password change
+		jti, _ := claims["jti"].(string)
+		issuedAt, _ := claims["iat"].(float64)
+		expiresAt, _ := claims["exp"].(float64)
+		if jti == "" || issuedAt == 0 {
+			log.Println("Token without jti or iat claim")
+			apierror.Write(w, r, apierror.ErrAuthTokenInvalid)
+			return
+		}
+		emailVerified, _ := claims["email_verified"].(bool)
+		role, _ := claims["role"].(string)
+		info := TokenInfo{
+			ID:            jti,
+			IssuedAt:      time.UnixMicro(int64(math.Round(issuedAt * 1e6))),
+			ExpiresAt:     time.Unix(int64(expiresAt), 0),
+			EmailVerified: emailVerified,
+			Role:          app.Role(role),
+		}
+		// Tokens issued before roles existed belong to regular users
+		if info.Role == "" {
+			info.Role = app.RoleUser
+		}
+		if revoked.IsRevoked(info.ID, userID, info.IssuedAt) {
+			log.Printf("Revoked token used for user ID %d", userID)
+			apierror.Write(w, r, apierror.ErrAuthTokenRevoked)
+			return
+		}
+
+		// Suspending or deleting an account also revokes its tokens, but the
+		// status is checked as well so accounts changed directly in the database
+		// are locked out too
+		status, err := accounts.UserStatus(userID)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal("Error checking account status", err))
+			return
+		}
+		switch status {
+		case app.UserSuspended:
+			apierror.Write(w, r, apierror.ErrAccountSuspended)
+			return
+		case app.UserDeleted:
+			apierror.Write(w, r, apierror.ErrAccountDeleted)
+			return
+		}
+
 		log.Printf("User ID from token: %d", userID)
 
-		// Add user ID to request context
+		// Add user ID and token info to request context
 		ctx := context.WithValue(r.Context(), userContextKey, userID)
+		ctx = context.WithValue(ctx, tokenContextKey, info)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -75,6 +144,11 @@ func JWTAuth(next http.Handler) http.Handler {
 // GetUserContextKey retrieves the user ID from request context
 func GetUserContextKey(r *http.Request) (uint, bool) {
 	userID, ok := r.Context().Value(userContextKey).(uint)
-	log.Printf("Retrieved user ID from context: %d, exists: %v", userID, ok)
 	return userID, ok
 }
+
+// GetTokenInfo retrieves the authenticating access token from request context
+func GetTokenInfo(r *http.Request) (TokenInfo, bool) {
+	info, ok := r.Context().Value(tokenContextKey).(TokenInfo)
+	return info, ok
+}
@@ -0,0 +1,46 @@
+package middlewares
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ResolveClientIP stores the IP address of the client in the request context
+// for ClientIP. Behind a reverse proxy, set trustProxy to use the address the
+// proxy appended to X-Forwarded-For; otherwise the header is ignored since
+// clients can forge it.
+func ResolveClientIP(trustProxy bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := remoteIP(r)
+			if trustProxy {
+				if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+					parts := strings.Split(forwarded, ",")
+					if last := strings.TrimSpace(parts[len(parts)-1]); net.ParseIP(last) != nil {
+						ip = last
+					}
+				}
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIPContextKey, ip)))
+		})
+	}
+}
+
+// ClientIP returns the IP address of the client that sent r, as resolved by
+// ResolveClientIP, or the address of the connection when it did not run
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPContextKey).(string); ok {
+		return ip
+	}
+	return remoteIP(r)
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,140 @@
+package middlewares
+
+import (
+	"backend-api/apierror"
+	"backend-api/config"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxRateLimitBuckets bounds the buckets of a RateLimiter; full buckets are
+// dropped when it is reached since they behave like new ones
+const maxRateLimitBuckets = 100000
+
+// RateLimiter is a token bucket rate limit for a group of routes. Each client
+// gets a bucket of Limit tokens that refills at Limit per Window; a request
+// takes one token. Clients are the authenticated user when the limiter runs
+// after JWTAuth, and the client IP otherwise.
+//
+// Buckets are kept in memory, so every instance enforces the limit on its own.
+type RateLimiter struct {
+	Name   string
+	Limit  int
+	Window time.Duration
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// NewRateLimiter returns a RateLimiter for the route group name allowing limit requests per window
+func NewRateLimiter(name string, limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{Name: name, Limit: limit, Window: window, buckets: map[string]*tokenBucket{}}
+}
+
+// RateLimiterFor returns the limiter of the route group name configured by
+// limit, or nil when the limit is off
+func RateLimiterFor(name string, limit config.RateLimit) *RateLimiter {
+	if !limit.Enabled() {
+		return nil
+	}
+	return NewRateLimiter(name, limit.Requests, limit.Window)
+}
+
+// rateLimitResult describes a bucket after a request took a token from it
+type rateLimitResult struct {
+	allowed   bool
+	remaining int
+	// reset is the time until the bucket is full again
+	reset time.Duration
+	// retryAfter is the time until the next token when the request was refused
+	retryAfter time.Duration
+}
+
+// take takes a token from the bucket of key
+func (l *RateLimiter) take(key string, now time.Time) rateLimitResult {
+	rate := float64(l.Limit) / l.Window.Seconds() // tokens per second
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= maxRateLimitBuckets {
+			l.dropFull(now, rate)
+		}
+		b = &tokenBucket{tokens: float64(l.Limit), updated: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(l.Limit), b.tokens+now.Sub(b.updated).Seconds()*rate)
+	b.updated = now
+
+	result := rateLimitResult{}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.allowed = true
+	} else {
+		result.retryAfter = secondsDuration((1 - b.tokens) / rate)
+	}
+	result.remaining = int(b.tokens)
+	result.reset = secondsDuration((float64(l.Limit) - b.tokens) / rate)
+	return result
+}
+
+// dropFull removes buckets that have refilled completely; l.mu must be held
+func (l *RateLimiter) dropFull(now time.Time, rate float64) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.updated).Seconds()*rate >= float64(l.Limit) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func secondsDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// ceilSeconds formats d as whole seconds, rounded up
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// RateLimit returns a middleware enforcing limiter, or a no-op middleware when
+// limiter is nil. Responses carry the RateLimit-Limit, RateLimit-Remaining,
+// RateLimit-Reset and RateLimit-Policy headers; refused requests get 429 with
+// Retry-After.
+func RateLimit(limiter *RateLimiter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if limiter == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := "ip:" + ClientIP(r)
+			if userID, ok := GetUserContextKey(r); ok {
+				key = "user:" + strconv.FormatUint(uint64(userID), 10)
+			}
+
+			result := limiter.take(key, time.Now())
+			header := w.Header()
+			header.Set("RateLimit-Limit", strconv.Itoa(limiter.Limit))
+			header.Set("RateLimit-Remaining", strconv.Itoa(result.remaining))
+			header.Set("RateLimit-Reset", ceilSeconds(result.reset))
+			header.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%s", limiter.Limit, ceilSeconds(limiter.Window)))
+
+			if !result.allowed {
+				header.Set("Retry-After", ceilSeconds(result.retryAfter))
+				apierror.Write(w, r, apierror.ErrRateLimited.WithDetail(fmt.Sprintf("Rate limit of %d requests per %s exceeded for %s routes", limiter.Limit, limiter.Window, limiter.Name)))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
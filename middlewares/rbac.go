@@ -0,0 +1,31 @@
+package middlewares
+
+import (
+	"backend-api/apierror"
+	"backend-api/app"
+	"log"
+	"net/http"
+)
+
+// RequirePermissions returns a middleware that rejects requests whose access token
+// belongs to a role without every permission in perms. It must run after JWTAuth.
+// The role is read from the token, so a changed role applies once the user gets a
+// new access token; role changes revoke existing tokens for that reason.
+func RequirePermissions(perms ...app.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			info, ok := GetTokenInfo(r)
+			if !ok {
+				apierror.Write(w, r, apierror.ErrAuthTokenInvalid)
+				return
+			}
+			if !info.Role.Can(perms...) {
+				userID, _ := GetUserContextKey(r)
+				log.Printf("User ID %d with role %q denied %v", userID, info.Role, perms)
+				apierror.Write(w, r, apierror.ErrForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
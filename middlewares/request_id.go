@@ -0,0 +1,51 @@
+package middlewares
+
+import (
+	"backend-api/helpers"
+	"context"
+	"net/http"
+)
+
+// RequestIDHeader carries the request ID in requests and responses
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds request IDs accepted from clients and proxies
+const maxRequestIDLength = 128
+
+// RequestID assigns every request an ID, reusing a valid X-Request-ID header
+// set by a client or proxy. The ID is echoed in the X-Request-ID response
+// header and included in error responses.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			generated, err := helpers.RandomID()
+			if err != nil {
+				generated = "unknown"
+			}
+			id = generated
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDContextKey, id)))
+	})
+}
+
+// GetRequestID retrieves the request ID from request context
+func GetRequestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDContextKey).(string)
+	return id
+}
+
+// validRequestID accepts short IDs of printable ASCII characters only
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,24 @@
+package middlewares
+
+import (
+	"backend-api/apierror"
+	"net/http"
+)
+
+// RequireVerifiedEmail rejects requests whose access token was issued before the
+// user verified their email. It must run after JWTAuth. A user who verifies
+// their email has to refresh the access token to pass this check.
+func RequireVerifiedEmail(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info, ok := GetTokenInfo(r)
+		if !ok {
+			apierror.Write(w, r, apierror.ErrAuthTokenInvalid)
+			return
+		}
+		if !info.EmailVerified {
+			apierror.Write(w, r, apierror.ErrEmailNotVerified)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
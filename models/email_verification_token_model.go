@@ -0,0 +1,57 @@
+package models
+
+import (
+	"backend-api/app"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrEmailVerificationTokenInvalid dikembalikan untuk token verifikasi email yang tidak
+// dikenal atau kedaluwarsa, dan untuk token milik email yang sudah diganti.
+var ErrEmailVerificationTokenInvalid = errors.New("email verification token is invalid or expired")
+
+// CreateEmailVerificationToken menyimpan token verifikasi email baru.
+func (repo *sqlTokenRepository) CreateEmailVerificationToken(token *app.EmailVerificationToken) error {
+	query := `INSERT INTO email_verification_tokens (user_id, email, token_hash, expires_at, created_at) VALUES (?, ?, ?, ?, ?)`
+	id, err := repo.insert(query, token.UserID, token.Email, token.TokenHash, token.ExpiresAt, token.CreatedAt)
+	if err != nil {
+		return err
+	}
+	token.ID = id
+	return nil
+}
+
+// VerifyEmail memakai token verifikasi email dengan hash tokenHash dan menandai email
+// pemiliknya terverifikasi sejak now, jika akunnya masih aktif dan emailnya belum diganti. Token dapat dipakai
+// berulang kali selama belum kedaluwarsa; email yang sudah terverifikasi tidak berubah.
+func (repo *sqlTokenRepository) VerifyEmail(tokenHash string, now time.Time) error {
+	return repo.withTx(func(tx sqlTx) error {
+		var userID uint
+		var email string
+		var expiresAt time.Time
+		query := `SELECT user_id, email, expires_at FROM email_verification_tokens WHERE token_hash = ?`
+		err := tx.queryRow(query, tokenHash).Scan(&userID, &email, &expiresAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrEmailVerificationTokenInvalid
+		}
+		if err != nil {
+			return err
+		}
+		if !now.Before(expiresAt) {
+			return ErrEmailVerificationTokenInvalid
+		}
+
+		var currentEmail string
+		err = tx.queryRow(`SELECT email FROM users WHERE id = ? AND deleted_at IS NULL`+tx.forUpdate(), userID).Scan(&currentEmail)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && currentEmail != email) {
+			return ErrEmailVerificationTokenInvalid
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.exec(`UPDATE users SET email_verified_at = ? WHERE id = ? AND email_verified_at IS NULL`, now, userID)
+		return err
+	})
+}
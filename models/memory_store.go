@@ -0,0 +1,652 @@
+package models
+
+import (
+	"backend-api/app"
+	"backend-api/redact"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStore menyimpan pengguna, foto dan token di memori. MemoryStore
+// mengimplementasikan UserRepository, PhotoRepository dan TokenRepository
+// sehingga controller dapat diuji tanpa database.
+type MemoryStore struct {
+	mu            sync.Mutex
+	users         map[uint]app.User
+	photos        map[uint]app.Photo
+	refreshTokens map[uint]app.RefreshToken
+	resetTokens   map[uint]app.PasswordResetToken
+	emailTokens   map[uint]app.EmailVerificationToken
+	revokedTokens map[string]time.Time
+	cutoffs       map[uint]time.Time
+	lastID        map[string]uint
+}
+
+// NewMemoryStore membuat MemoryStore kosong.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		users:         map[uint]app.User{},
+		photos:        map[uint]app.Photo{},
+		refreshTokens: map[uint]app.RefreshToken{},
+		resetTokens:   map[uint]app.PasswordResetToken{},
+		emailTokens:   map[uint]app.EmailVerificationToken{},
+		revokedTokens: map[string]time.Time{},
+		cutoffs:       map[uint]time.Time{},
+		lastID:        map[string]uint{},
+	}
+}
+
+// newID menghasilkan ID auto increment per tabel; m.mu harus sudah dikunci.
+func (m *MemoryStore) newID(table string) uint {
+	m.lastID[table]++
+	return m.lastID[table]
+}
+
+func (m *MemoryStore) CreateUser(user *app.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	normalizeUser(user)
+	if err := m.checkUnique(user, 0); err != nil {
+		return err
+	}
+	user.ID = m.newID("users")
+	m.users[user.ID] = *user
+	return nil
+}
+
+func (m *MemoryStore) GetUserByEmail(email string) (app.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	email = NormalizeEmail(email)
+	for _, user := range m.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return app.User{}, ErrNotFound
+}
+
+func (m *MemoryStore) GetUserByID(userID uint) (app.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[userID]
+	if !ok {
+		return app.User{}, ErrNotFound
+	}
+	return user, nil
+}
+
+func (m *MemoryStore) GetUserWithPhotos(userID uint, profileOnly bool) (app.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[userID]
+	if !ok {
+		return app.User{}, ErrNotFound
+	}
+	user.Photos = m.userPhotos(userID, profileOnly)
+	sortPhotos(user.Photos)
+	return user, nil
+}
+
+func (m *MemoryStore) ListUsers(limit, offset int) ([]app.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	users := make([]app.User, 0, len(m.users))
+	for _, user := range m.users {
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		if !users[i].CreatedAt.Equal(users[j].CreatedAt) {
+			return users[i].CreatedAt.After(users[j].CreatedAt)
+		}
+		return users[i].ID > users[j].ID
+	})
+	return page(users, limit, offset), nil
+}
+
+func (m *MemoryStore) CountUsers() (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.users), nil
+}
+
+func (m *MemoryStore) SetUserRole(userID uint, role app.Role) error {
+	return m.modifyUser(userID, func(user *app.User) { user.Role = role })
+}
+
+func (m *MemoryStore) SetUserSuspended(userID uint, at *time.Time) error {
+	return m.modifyUser(userID, func(user *app.User) { user.SuspendedAt = at })
+}
+
+// modifyUser menerapkan fn pada pengguna userID dan memperbarui updated_at.
+func (m *MemoryStore) modifyUser(userID uint, fn func(user *app.User)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[userID]
+	if !ok {
+		return ErrNotFound
+	}
+	fn(&user)
+	user.UpdatedAt = time.Now()
+	m.users[userID] = user
+	return nil
+}
+
+func (m *MemoryStore) UpdateUser(userID uint, update app.UserUpdate) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.users[userID]
+	if !ok {
+		return nil
+	}
+	normalizeUserUpdate(&update)
+	changed := existing
+	if update.Email != nil {
+		changed.Email = *update.Email
+	}
+	if update.Username != nil {
+		changed.Username = *update.Username
+	}
+	if update.Password != nil {
+		changed.Password = *update.Password
+	}
+	if err := m.checkUnique(&changed, userID); err != nil {
+		return err
+	}
+	if changed.Email != existing.Email {
+		changed.EmailVerifiedAt = nil
+	}
+	changed.UpdatedAt = update.UpdatedAt
+	m.users[userID] = changed
+	return nil
+}
+
+// checkUnique meniru constraint UNIQUE pada email dan username; m.mu harus sudah dikunci.
+func (m *MemoryStore) checkUnique(user *app.User, excludeID uint) error {
+	for id, other := range m.users {
+		if id == excludeID {
+			continue
+		}
+		if other.Email == user.Email {
+			return ErrEmailTaken
+		}
+		if user.Username != "" && strings.EqualFold(other.Username, user.Username) {
+			return ErrUsernameTaken
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) MarkEmailVerified(userID uint, email string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[userID]
+	if ok && user.Email == email && user.EmailVerifiedAt == nil {
+		user.EmailVerifiedAt = &at
+		m.users[userID] = user
+	}
+	return nil
+}
+
+func (m *MemoryStore) DeleteUser(userID uint, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[userID]
+	if ok && user.DeletedAt == nil {
+		user.DeletedAt = &at
+		user.UpdatedAt = at
+		m.users[userID] = user
+	}
+	return nil
+}
+
+func (m *MemoryStore) RestoreUser(userID uint) error {
+	return m.modifyUser(userID, func(user *app.User) { user.DeletedAt = nil })
+}
+
+func (m *MemoryStore) DeletedUsersBefore(before time.Time, limit int) ([]uint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ids := []uint{}
+	for id, user := range m.users {
+		if user.DeletedAt != nil && user.DeletedAt.Before(before) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return page(ids, limit, 0), nil
+}
+
+func (m *MemoryStore) PurgeUser(userID uint, before time.Time) ([]app.Photo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[userID]
+	if !ok || user.DeletedAt == nil || !user.DeletedAt.Before(before) {
+		return nil, ErrNotFound
+	}
+
+	photos := m.userPhotos(userID, false)
+	delete(m.users, userID)
+	// Sama seperti ON DELETE CASCADE
+	for id, photo := range m.photos {
+		if photo.UserID == userID {
+			delete(m.photos, id)
+		}
+	}
+	for id, token := range m.refreshTokens {
+		if token.UserID == userID {
+			delete(m.refreshTokens, id)
+		}
+	}
+	for id, token := range m.resetTokens {
+		if token.UserID == userID {
+			delete(m.resetTokens, id)
+		}
+	}
+	return photos, nil
+}
+
+func (m *MemoryStore) CreatePhoto(photo *app.Photo) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	photo.ID = m.newID("photos")
+	m.photos[photo.ID] = *photo
+	return nil
+}
+
+func (m *MemoryStore) CreateProfilePhoto(photo *app.Photo) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.unsetProfilePhoto(photo.UserID, photo.UpdatedAt)
+	photo.ID = m.newID("photos")
+	photo.IsProfile = true
+	m.photos[photo.ID] = *photo
+	return nil
+}
+
+func (m *MemoryStore) UpdatePhoto(photo *app.Photo) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.photos[photo.ID]
+	if !ok || existing.UserID != photo.UserID {
+		return nil
+	}
+	existing.PhotoURL = photo.PhotoURL
+	existing.IsProfile = photo.IsProfile
+	existing.UpdatedAt = photo.UpdatedAt
+	m.photos[photo.ID] = existing
+	return nil
+}
+
+func (m *MemoryStore) DeletePhoto(photoID, userID uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if photo, ok := m.photos[photoID]; ok && photo.UserID == userID {
+		delete(m.photos, photoID)
+	}
+	return nil
+}
+
+func (m *MemoryStore) GetPhotoByID(photoID, userID uint) (*app.Photo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	photo, ok := m.photos[photoID]
+	if !ok || photo.UserID != userID {
+		return nil, ErrNotFound
+	}
+	return &photo, nil
+}
+
+func (m *MemoryStore) GetUserPhotos(userID uint, limit, offset int) ([]app.Photo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	photos := m.userPhotos(userID, false)
+	sortPhotos(photos)
+	return page(photos, limit, offset), nil
+}
+
+// page mengembalikan bagian items sesuai limit dan offset.
+func page[T any](items []T, limit, offset int) []T {
+	if offset >= len(items) {
+		return []T{}
+	}
+	items = items[offset:]
+	if limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
+
+func (m *MemoryStore) FindPhoto(photoID uint) (*app.Photo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	photo, ok := m.photos[photoID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &photo, nil
+}
+
+func (m *MemoryStore) ListPhotos(limit, offset int) ([]app.Photo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	photos := make([]app.Photo, 0, len(m.photos))
+	for _, photo := range m.photos {
+		photos = append(photos, photo)
+	}
+	sortPhotos(photos)
+	return page(photos, limit, offset), nil
+}
+
+func (m *MemoryStore) CountPhotos() (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.photos), nil
+}
+
+func (m *MemoryStore) CountUserPhotos(userID uint) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.userPhotos(userID, false)), nil
+}
+
+func (m *MemoryStore) CountPhotosByURL(photoURL string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	total := 0
+	for _, photo := range m.photos {
+		if photo.PhotoURL == photoURL {
+			total++
+		}
+	}
+	return total, nil
+}
+
+func (m *MemoryStore) GetUserProfilePhotos(userID uint) ([]app.Photo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.userPhotos(userID, true), nil
+}
+
+func (m *MemoryStore) GetUserProfilePhoto(userID uint) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	photos := m.userPhotos(userID, true)
+	if len(photos) == 0 {
+		return "", ErrNotFound
+	}
+	return photos[0].PhotoURL, nil
+}
+
+func (m *MemoryStore) SetProfilePhoto(photoID, userID uint) (*app.Photo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	photo, ok := m.photos[photoID]
+	if !ok || photo.UserID != userID {
+		return nil, ErrNotFound
+	}
+
+	now := time.Now()
+	m.unsetProfilePhoto(userID, now)
+	photo.IsProfile = true
+	photo.UpdatedAt = now
+	m.photos[photoID] = photo
+	return &photo, nil
+}
+
+// userPhotos mengembalikan foto milik userID; m.mu harus sudah dikunci.
+func (m *MemoryStore) userPhotos(userID uint, profileOnly bool) []app.Photo {
+	photos := []app.Photo{}
+	for _, photo := range m.photos {
+		if photo.UserID == userID && (!profileOnly || photo.IsProfile) {
+			photos = append(photos, photo)
+		}
+	}
+	return photos
+}
+
+// sortPhotos mengurutkan foto dari yang terbaru, seperti query SQL.
+func sortPhotos(photos []app.Photo) {
+	sort.Slice(photos, func(i, j int) bool {
+		if !photos[i].CreatedAt.Equal(photos[j].CreatedAt) {
+			return photos[i].CreatedAt.After(photos[j].CreatedAt)
+		}
+		return photos[i].ID > photos[j].ID
+	})
+}
+
+// unsetProfilePhoto melepas status foto profil milik userID; m.mu harus sudah dikunci.
+func (m *MemoryStore) unsetProfilePhoto(userID uint, updatedAt time.Time) {
+	for id, photo := range m.photos {
+		if photo.UserID == userID && photo.IsProfile {
+			photo.IsProfile = false
+			photo.UpdatedAt = updatedAt
+			m.photos[id] = photo
+		}
+	}
+}
+
+func (m *MemoryStore) CreateRefreshToken(token *app.RefreshToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	token.ID = m.newID("refresh_tokens")
+	m.refreshTokens[token.ID] = *token
+	return nil
+}
+
+func (m *MemoryStore) RotateRefreshToken(oldHash string, next *app.RefreshToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var old app.RefreshToken
+	found := false
+	for _, token := range m.refreshTokens {
+		if token.TokenHash == oldHash {
+			old, found = token, true
+			break
+		}
+	}
+	if !found {
+		return ErrRefreshTokenInvalid
+	}
+
+	now := time.Now()
+	if old.RevokedAt != nil {
+		m.revokeRefreshTokens(func(token app.RefreshToken) bool { return token.FamilyID == old.FamilyID }, now)
+		return ErrRefreshTokenReused
+	}
+	if !now.Before(old.ExpiresAt) {
+		return ErrRefreshTokenInvalid
+	}
+
+	old.RevokedAt = &now
+	m.refreshTokens[old.ID] = old
+
+	next.ID = m.newID("refresh_tokens")
+	next.UserID = old.UserID
+	next.FamilyID = old.FamilyID
+	m.refreshTokens[next.ID] = *next
+	return nil
+}
+
+func (m *MemoryStore) RevokeRefreshTokenFamily(tokenHash string, userID uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, token := range m.refreshTokens {
+		if token.TokenHash == tokenHash && token.UserID == userID {
+			familyID := token.FamilyID
+			m.revokeRefreshTokens(func(token app.RefreshToken) bool { return token.FamilyID == familyID }, time.Now())
+			break
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) RevokeUserRefreshTokens(userID uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.revokeRefreshTokens(func(token app.RefreshToken) bool { return token.UserID == userID }, time.Now())
+	return nil
+}
+
+// revokeRefreshTokens mencabut refresh token aktif yang cocok; m.mu harus sudah dikunci.
+func (m *MemoryStore) revokeRefreshTokens(match func(app.RefreshToken) bool, now time.Time) {
+	for id, token := range m.refreshTokens {
+		if token.RevokedAt == nil && match(token) {
+			token.RevokedAt = &now
+			m.refreshTokens[id] = token
+		}
+	}
+}
+
+func (m *MemoryStore) CreatePasswordResetToken(token *app.PasswordResetToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	token.ID = m.newID("password_reset_tokens")
+	m.resetTokens[token.ID] = *token
+	return nil
+}
+
+func (m *MemoryStore) ResetPassword(tokenHash string, passwordHash redact.Secret, now time.Time) (uint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, token := range m.resetTokens {
+		if token.TokenHash != tokenHash {
+			continue
+		}
+		user, ok := m.users[token.UserID]
+		if !ok || token.UsedAt != nil || !now.Before(token.ExpiresAt) {
+			return 0, ErrPasswordResetTokenInvalid
+		}
+		for id, other := range m.resetTokens {
+			if other.UserID == token.UserID && other.UsedAt == nil {
+				other.UsedAt = &now
+				m.resetTokens[id] = other
+			}
+		}
+		user.Password = passwordHash
+		user.UpdatedAt = now
+		m.users[user.ID] = user
+		return user.ID, nil
+	}
+	return 0, ErrPasswordResetTokenInvalid
+}
+
+func (m *MemoryStore) CreateEmailVerificationToken(token *app.EmailVerificationToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	token.ID = m.newID("email_verification_tokens")
+	m.emailTokens[token.ID] = *token
+	return nil
+}
+
+func (m *MemoryStore) VerifyEmail(tokenHash string, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, token := range m.emailTokens {
+		if token.TokenHash != tokenHash {
+			continue
+		}
+		user, ok := m.users[token.UserID]
+		if !ok || user.DeletedAt != nil || user.Email != token.Email || !now.Before(token.ExpiresAt) {
+			return ErrEmailVerificationTokenInvalid
+		}
+		if user.EmailVerifiedAt == nil {
+			user.EmailVerifiedAt = &now
+			m.users[user.ID] = user
+		}
+		return nil
+	}
+	return ErrEmailVerificationTokenInvalid
+}
+
+func (m *MemoryStore) RevokeToken(jti string, userID uint, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.revokedTokens[jti] = expiresAt
+	return nil
+}
+
+func (m *MemoryStore) GetRevokedTokens(now time.Time) (map[string]time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tokens := map[string]time.Time{}
+	for jti, expiresAt := range m.revokedTokens {
+		if expiresAt.After(now) {
+			tokens[jti] = expiresAt
+		}
+	}
+	return tokens, nil
+}
+
+func (m *MemoryStore) DeleteExpiredRevokedTokens(now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for jti, expiresAt := range m.revokedTokens {
+		if !expiresAt.After(now) {
+			delete(m.revokedTokens, jti)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) SetUserTokenCutoff(userID uint, before time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if before.After(m.cutoffs[userID]) {
+		m.cutoffs[userID] = before
+	}
+	return nil
+}
+
+func (m *MemoryStore) GetUserTokenCutoffs(since time.Time) (map[uint]time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cutoffs := map[uint]time.Time{}
+	for userID, before := range m.cutoffs {
+		if before.After(since) {
+			cutoffs[userID] = before
+		}
+	}
+	return cutoffs, nil
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"backend-api/app"
+	"backend-api/redact"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrPasswordResetTokenInvalid dikembalikan untuk token reset password yang tidak dikenal,
+// sudah dipakai, atau kedaluwarsa.
+var ErrPasswordResetTokenInvalid = errors.New("password reset token is invalid or expired")
+
+// CreatePasswordResetToken menyimpan token reset password baru.
+func (repo *sqlTokenRepository) CreatePasswordResetToken(token *app.PasswordResetToken) error {
+	query := `INSERT INTO password_reset_tokens (user_id, token_hash, expires_at, created_at) VALUES (?, ?, ?, ?)`
+	id, err := repo.insert(query, token.UserID, token.TokenHash, token.ExpiresAt, token.CreatedAt)
+	if err != nil {
+		return err
+	}
+	token.ID = id
+	return nil
+}
+
+// ResetPassword memakai token reset password dengan hash tokenHash dan mengganti password
+// pemiliknya dengan passwordHash dalam satu transaksi. Semua token reset lain milik
+// pengguna tersebut ikut ditandai terpakai. ID pengguna dikembalikan.
+func (repo *sqlTokenRepository) ResetPassword(tokenHash string, passwordHash redact.Secret, now time.Time) (uint, error) {
+	var userID uint
+	err := repo.withTx(func(tx sqlTx) error {
+		var expiresAt time.Time
+		var usedAt sql.NullTime
+		query := `SELECT user_id, expires_at, used_at FROM password_reset_tokens WHERE token_hash = ?` + tx.forUpdate()
+		err := tx.queryRow(query, tokenHash).Scan(&userID, &expiresAt, &usedAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrPasswordResetTokenInvalid
+		}
+		if err != nil {
+			return err
+		}
+
+		if usedAt.Valid || !now.Before(expiresAt) {
+			return ErrPasswordResetTokenInvalid
+		}
+
+		if _, err := tx.exec(`UPDATE password_reset_tokens SET used_at = ? WHERE user_id = ? AND used_at IS NULL`, now, userID); err != nil {
+			return err
+		}
+		_, err = tx.exec(`UPDATE users SET password = ?, updated_at = ? WHERE id = ?`, passwordHash.Reveal(), now, userID)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return userID, nil
+}
@@ -3,109 +3,207 @@ package models
 import (
 	"backend-api/app"
 	"backend-api/database"
+	"database/sql"
+	"errors"
 	"log"
 	"time"
 )
 
-// CreatePhoto menambahkan foto profil baru untuk pengguna.
-func CreatePhoto(photo *app.Photo) error {
+// ErrProfilePhotoConflict dikembalikan ketika perubahan foto profil bertabrakan
+// dengan permintaan lain yang berjalan bersamaan.
+var ErrProfilePhotoConflict = errors.New("profile photo was changed concurrently")
+
+// sqlPhotoRepository adalah PhotoRepository yang disimpan di database SQL.
+type sqlPhotoRepository struct {
+	sqlStore
+}
+
+// NewSQLPhotoRepository membuat PhotoRepository di atas koneksi db dengan dialek dialect.
+func NewSQLPhotoRepository(db *sql.DB, dialect database.Dialect) PhotoRepository {
+	return &sqlPhotoRepository{sqlStore{db: db, dialect: dialect}}
+}
+
+// CreatePhoto menambahkan foto baru untuk pengguna.
+func (repo *sqlPhotoRepository) CreatePhoto(photo *app.Photo) error {
 	query := `INSERT INTO photos (photo_url, user_id, is_profile, created_at, updated_at) VALUES (?, ?, ?, ?, ?)`
-	result, err := database.DB.Exec(query, photo.PhotoURL, photo.UserID, photo.IsProfile, photo.CreatedAt, photo.UpdatedAt)
-	if err != nil {
-		return err
-	}
-	lastInsertID, err := result.LastInsertId()
+	id, err := repo.insert(query, photo.PhotoURL, photo.UserID, photo.IsProfile, photo.CreatedAt, photo.UpdatedAt)
 	if err != nil {
 		return err
 	}
-	photo.ID = uint(lastInsertID)
+	photo.ID = id
 	return nil
 }
 
 // UpdatePhoto memperbarui informasi foto yang sudah ada.
-func UpdatePhoto(photo *app.Photo) error {
+func (repo *sqlPhotoRepository) UpdatePhoto(photo *app.Photo) error {
 	query := `UPDATE photos SET photo_url = ?, is_profile = ?, updated_at = ? WHERE id = ? AND user_id = ?`
-	_, err := database.DB.Exec(query, photo.PhotoURL, photo.IsProfile, photo.UpdatedAt, photo.ID, photo.UserID)
+	_, err := repo.exec(query, photo.PhotoURL, photo.IsProfile, photo.UpdatedAt, photo.ID, photo.UserID)
 	return err
 }
 
 // DeletePhoto menghapus foto dengan ID tertentu untuk pengguna tertentu.
-func DeletePhoto(photoID, userID uint) error {
+func (repo *sqlPhotoRepository) DeletePhoto(photoID, userID uint) error {
 	query := `DELETE FROM photos WHERE id = ? AND user_id = ?`
-	_, err := database.DB.Exec(query, photoID, userID)
+	_, err := repo.exec(query, photoID, userID)
 	return err
 }
 
 // GetPhotoByID retrieves the details of a photo by photo ID and user ID.
-func GetPhotoByID(photoID, userID uint) (*app.Photo, error) {
-	var photo app.Photo
-	var createdAtStr, updatedAtStr string
-	query := `SELECT id, photo_url, user_id, is_profile, created_at, updated_at FROM photos WHERE id = ? AND user_id = ? LIMIT 1`
-	err := database.DB.QueryRow(query, photoID, userID).Scan(&photo.ID, &photo.PhotoURL, &photo.UserID, &photo.IsProfile, &createdAtStr, &updatedAtStr)
+func (repo *sqlPhotoRepository) GetPhotoByID(photoID, userID uint) (*app.Photo, error) {
+	query := `SELECT ` + photoColumns + ` FROM photos WHERE id = ? AND user_id = ? LIMIT 1`
+	photo, err := scanPhoto(repo.queryRow(query, photoID, userID))
 	if err != nil {
 		log.Printf("Error fetching photo with ID %d for user ID %d: %v", photoID, userID, err)
-		return nil, err
+		return nil, notFound(err)
 	}
+	return &photo, nil
+}
 
-	// Convert createdAtStr and updatedAtStr to time.Time
-	photo.CreatedAt, err = time.Parse("2006-01-02 15:04:05", createdAtStr)
+// FindPhoto membaca foto berdasarkan ID saja, apa pun pemiliknya.
+func (repo *sqlPhotoRepository) FindPhoto(photoID uint) (*app.Photo, error) {
+	photos, err := repo.queryPhotos(`SELECT `+photoColumns+` FROM photos WHERE id = ?`, photoID)
 	if err != nil {
-		log.Printf("Error parsing created_at for photo with ID %d: %v", photoID, err)
 		return nil, err
 	}
-	photo.UpdatedAt, err = time.Parse("2006-01-02 15:04:05", updatedAtStr)
-	if err != nil {
-		log.Printf("Error parsing updated_at for photo with ID %d: %v", photoID, err)
-		return nil, err
+	if len(photos) == 0 {
+		return nil, ErrNotFound
 	}
+	return &photos[0], nil
+}
 
-	return &photo, nil
+// ListPhotos mengembalikan foto semua pengguna, terbaru lebih dulu, dengan paginasi.
+func (repo *sqlPhotoRepository) ListPhotos(limit, offset int) ([]app.Photo, error) {
+	query := `SELECT ` + photoColumns + ` FROM photos ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?`
+	return repo.queryPhotos(query, limit, offset)
+}
+
+// CountPhotos mengembalikan jumlah seluruh foto.
+func (repo *sqlPhotoRepository) CountPhotos() (int, error) {
+	var total int
+	err := repo.queryRow(`SELECT COUNT(*) FROM photos`).Scan(&total)
+	return total, err
 }
 
 // GetUserProfilePhotos mengembalikan semua foto profil untuk pengguna tertentu.
-func GetUserProfilePhotos(userID uint) ([]app.Photo, error) {
-	rows, err := database.DB.Query("SELECT id, photo_url, user_id, is_profile, created_at, updated_at FROM photos WHERE user_id = ? AND is_profile = true", userID)
+func (repo *sqlPhotoRepository) GetUserProfilePhotos(userID uint) ([]app.Photo, error) {
+	photos, err := repo.queryPhotos(`SELECT `+photoColumns+` FROM photos WHERE user_id = ? AND is_profile = true`, userID)
 	if err != nil {
 		log.Printf("Error fetching profile photos for user ID %d: %v", userID, err)
 		return nil, err
 	}
-	defer rows.Close()
-
-	var photos []app.Photo
-	for rows.Next() {
-		var photo app.Photo
-		var createdAtStr, updatedAtStr string
-		if err := rows.Scan(&photo.ID, &photo.PhotoURL, &photo.UserID, &photo.IsProfile, &createdAtStr, &updatedAtStr); err != nil {
-			log.Printf("Error scanning profile photo for user ID %d: %v", userID, err)
-			return nil, err
-		}
-
-		// Convert createdAtStr and updatedAtStr to time.Time
-		photo.CreatedAt, err = time.Parse("2006-01-02 15:04:05", createdAtStr)
-		if err != nil {
-			log.Printf("Error parsing created_at for photo with ID %d: %v", photo.ID, err)
-			return nil, err
-		}
-		photo.UpdatedAt, err = time.Parse("2006-01-02 15:04:05", updatedAtStr)
-		if err != nil {
-			log.Printf("Error parsing updated_at for photo with ID %d: %v", photo.ID, err)
-			return nil, err
-		}
+	return photos, nil
+}
 
-		photos = append(photos, photo)
+// GetUserPhotos mengembalikan foto-foto pengguna, terbaru lebih dulu, dengan paginasi.
+func (repo *sqlPhotoRepository) GetUserPhotos(userID uint, limit, offset int) ([]app.Photo, error) {
+	query := `SELECT ` + photoColumns + ` FROM photos WHERE user_id = ? ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?`
+	photos, err := repo.queryPhotos(query, userID, limit, offset)
+	if err != nil {
+		log.Printf("Error fetching photos for user ID %d: %v", userID, err)
+		return nil, err
 	}
-
 	return photos, nil
 }
 
+// CountUserPhotos mengembalikan jumlah seluruh foto milik pengguna.
+func (repo *sqlPhotoRepository) CountUserPhotos(userID uint) (int, error) {
+	var total int
+	err := repo.queryRow(`SELECT COUNT(*) FROM photos WHERE user_id = ?`, userID).Scan(&total)
+	return total, err
+}
+
+// CountPhotosByURL mengembalikan jumlah foto, milik pengguna mana pun, yang memakai photoURL.
+func (repo *sqlPhotoRepository) CountPhotosByURL(photoURL string) (int, error) {
+	var total int
+	err := repo.queryRow(`SELECT COUNT(*) FROM photos WHERE photo_url = ?`, photoURL).Scan(&total)
+	return total, err
+}
+
+// queryPhotos menjalankan query yang memilih kolom-kolom foto dan memindai hasilnya.
+func (repo *sqlPhotoRepository) queryPhotos(query string, args ...interface{}) ([]app.Photo, error) {
+	rows, err := repo.query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanPhotos(rows)
+}
+
 // GetUserProfilePhoto mengembalikan URL foto profil untuk pengguna tertentu.
-func GetUserProfilePhoto(userID uint) (string, error) {
+func (repo *sqlPhotoRepository) GetUserProfilePhoto(userID uint) (string, error) {
 	var photoURL string
 	query := `SELECT photo_url FROM photos WHERE user_id = ? AND is_profile = true LIMIT 1`
-	err := database.DB.QueryRow(query, userID).Scan(&photoURL)
+	err := repo.queryRow(query, userID).Scan(&photoURL)
 	if err != nil {
 		log.Printf("Error fetching profile photo URL for user ID %d: %v", userID, err)
-		return "", err
+		return "", notFound(err)
 	}
 	return photoURL, nil
 }
+
+// CreateProfilePhoto menambahkan foto baru sebagai foto profil dan melepas
+// status profil dari foto profil sebelumnya dalam satu transaksi.
+func (repo *sqlPhotoRepository) CreateProfilePhoto(photo *app.Photo) error {
+	err := repo.withTx(func(tx sqlTx) error {
+		if err := lockUser(tx, photo.UserID); err != nil {
+			return err
+		}
+
+		if _, err := tx.exec(`UPDATE photos SET is_profile = false, updated_at = ? WHERE user_id = ? AND is_profile = true`, photo.UpdatedAt, photo.UserID); err != nil {
+			return err
+		}
+
+		photo.IsProfile = true
+		query := `INSERT INTO photos (photo_url, user_id, is_profile, created_at, updated_at) VALUES (?, ?, ?, ?, ?)`
+		id, err := tx.insert(query, photo.PhotoURL, photo.UserID, photo.IsProfile, photo.CreatedAt, photo.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		photo.ID = id
+		return nil
+	})
+	if repo.isDuplicateKey(err) {
+		return ErrProfilePhotoConflict
+	}
+	return err
+}
+
+// SetProfilePhoto menjadikan foto yang sudah ada sebagai foto profil pengguna.
+// Foto profil lama dan foto baru diubah dalam satu transaksi sehingga pengguna
+// tidak pernah memiliki dua foto profil.
+func (repo *sqlPhotoRepository) SetProfilePhoto(photoID, userID uint) (*app.Photo, error) {
+	err := repo.withTx(func(tx sqlTx) error {
+		if err := lockUser(tx, userID); err != nil {
+			return err
+		}
+
+		var exists bool
+		err := tx.queryRow(`SELECT true FROM photos WHERE id = ? AND user_id = ?`+tx.forUpdate(), photoID, userID).Scan(&exists)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if _, err := tx.exec(`UPDATE photos SET is_profile = false, updated_at = ? WHERE user_id = ? AND is_profile = true AND id <> ?`, now, userID, photoID); err != nil {
+			return err
+		}
+		if _, err := tx.exec(`UPDATE photos SET is_profile = true, updated_at = ? WHERE id = ? AND user_id = ?`, now, photoID, userID); err != nil {
+			return err
+		}
+		return nil
+	})
+	if repo.isDuplicateKey(err) {
+		return nil, ErrProfilePhotoConflict
+	}
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	return repo.GetPhotoByID(photoID, userID)
+}
+
+// lockUser mengunci baris pengguna sampai transaksi selesai sehingga perubahan
+// foto profil untuk pengguna yang sama dijalankan bergantian.
+func lockUser(tx sqlTx, userID uint) error {
+	var id uint
+	return tx.queryRow(`SELECT id FROM users WHERE id = ?`+tx.forUpdate(), userID).Scan(&id)
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"backend-api/app"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrRefreshTokenInvalid dikembalikan untuk refresh token yang tidak dikenal, dicabut, atau kedaluwarsa.
+	ErrRefreshTokenInvalid = errors.New("refresh token is invalid or expired")
+	// ErrRefreshTokenReused dikembalikan ketika refresh token yang sudah dirotasi dipakai lagi;
+	// seluruh keluarga token tersebut sudah dicabut.
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+)
+
+// CreateRefreshToken menyimpan refresh token baru.
+func (repo *sqlTokenRepository) CreateRefreshToken(token *app.RefreshToken) error {
+	query := `INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at, created_at) VALUES (?, ?, ?, ?, ?)`
+	id, err := repo.insert(query, token.UserID, token.FamilyID, token.TokenHash, token.ExpiresAt, token.CreatedAt)
+	if err != nil {
+		return err
+	}
+	token.ID = id
+	return nil
+}
+
+// RotateRefreshToken mencabut refresh token dengan hash oldHash dan menyimpan next
+// sebagai penggantinya dalam keluarga dan untuk pengguna yang sama.
+// Jika token lama sudah pernah dicabut, seluruh keluarganya dicabut dan
+// ErrRefreshTokenReused dikembalikan.
+func (repo *sqlTokenRepository) RotateRefreshToken(oldHash string, next *app.RefreshToken) error {
+	reused := false
+	err := repo.withTx(func(tx sqlTx) error {
+		var id, userID uint
+		var familyID string
+		var expiresAt time.Time
+		var revokedAt sql.NullTime
+		query := `SELECT id, user_id, family_id, expires_at, revoked_at FROM refresh_tokens WHERE token_hash = ?` + tx.forUpdate()
+		err := tx.queryRow(query, oldHash).Scan(&id, &userID, &familyID, &expiresAt, &revokedAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRefreshTokenInvalid
+		}
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if revokedAt.Valid {
+			// Token lama dipakai ulang: kemungkinan dicuri, cabut seluruh keluarga
+			reused = true
+			_, err := tx.exec(`UPDATE refresh_tokens SET revoked_at = ? WHERE family_id = ? AND revoked_at IS NULL`, now, familyID)
+			return err
+		}
+
+		if !now.Before(expiresAt) {
+			return ErrRefreshTokenInvalid
+		}
+
+		if _, err := tx.exec(`UPDATE refresh_tokens SET revoked_at = ? WHERE id = ?`, now, id); err != nil {
+			return err
+		}
+
+		next.UserID = userID
+		next.FamilyID = familyID
+		insertQuery := `INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at, created_at) VALUES (?, ?, ?, ?, ?)`
+		nextID, err := tx.insert(insertQuery, next.UserID, next.FamilyID, next.TokenHash, next.ExpiresAt, next.CreatedAt)
+		if err != nil {
+			return err
+		}
+		next.ID = nextID
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if reused {
+		return ErrRefreshTokenReused
+	}
+	return nil
+}
+
+// RevokeRefreshTokenFamily mencabut keluarga refresh token milik userID yang memuat token dengan hash tokenHash.
+func (repo *sqlTokenRepository) RevokeRefreshTokenFamily(tokenHash string, userID uint) error {
+	query := `UPDATE refresh_tokens SET revoked_at = ?
+        WHERE revoked_at IS NULL AND user_id = ? AND family_id = (
+            SELECT family_id FROM (SELECT family_id FROM refresh_tokens WHERE token_hash = ?) AS t
+        )`
+	_, err := repo.exec(query, time.Now(), userID, tokenHash)
+	return err
+}
+
+// RevokeUserRefreshTokens mencabut semua refresh token aktif milik pengguna.
+func (repo *sqlTokenRepository) RevokeUserRefreshTokens(userID uint) error {
+	_, err := repo.exec(`UPDATE refresh_tokens SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL`, time.Now(), userID)
+	return err
+}
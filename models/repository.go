@@ -0,0 +1,128 @@
+package models
+
+import (
+	"backend-api/app"
+	"backend-api/redact"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotFound dikembalikan ketika data yang diminta tidak ada.
+	ErrNotFound = errors.New("record not found")
+	// ErrDuplicate dikembalikan ketika data melanggar constraint UNIQUE.
+	ErrDuplicate = errors.New("record already exists")
+	// ErrEmailTaken dikembalikan ketika email sudah dipakai pengguna lain; membungkus ErrDuplicate.
+	ErrEmailTaken = fmt.Errorf("%w: email is already registered", ErrDuplicate)
+	// ErrUsernameTaken dikembalikan ketika username sudah dipakai pengguna lain; membungkus ErrDuplicate.
+	ErrUsernameTaken = fmt.Errorf("%w: username is already taken", ErrDuplicate)
+)
+
+// UserRepository menyimpan dan membaca data pengguna.
+type UserRepository interface {
+	CreateUser(user *app.User) error
+	GetUserByEmail(email string) (app.User, error)
+	GetUserByID(userID uint) (app.User, error)
+	// GetUserWithPhotos membaca pengguna beserta fotonya (atau hanya foto profilnya) dalam satu query.
+	GetUserWithPhotos(userID uint, profileOnly bool) (app.User, error)
+	// UpdateUser hanya mengubah field update yang tidak nil.
+	UpdateUser(userID uint, update app.UserUpdate) error
+	// MarkEmailVerified menandai email pengguna terverifikasi jika emailnya masih email.
+	MarkEmailVerified(userID uint, email string, at time.Time) error
+	// DeleteUser menghapus pengguna secara soft delete; lihat RestoreUser dan PurgeUser.
+	DeleteUser(userID uint, at time.Time) error
+	RestoreUser(userID uint) error
+	DeletedUsersBefore(before time.Time, limit int) ([]uint, error)
+	// PurgeUser menghapus permanen pengguna yang dihapus sebelum before dan mengembalikan fotonya.
+	PurgeUser(userID uint, before time.Time) ([]app.Photo, error)
+
+	ListUsers(limit, offset int) ([]app.User, error)
+	CountUsers() (int, error)
+	SetUserRole(userID uint, role app.Role) error
+	// SetUserSuspended menangguhkan pengguna sejak at; at nil mencabut penangguhan.
+	SetUserSuspended(userID uint, at *time.Time) error
+}
+
+// PhotoRepository menyimpan dan membaca foto pengguna.
+type PhotoRepository interface {
+	CreatePhoto(photo *app.Photo) error
+	// CreateProfilePhoto menambahkan foto sebagai foto profil dan melepas foto profil lama secara atomik.
+	CreateProfilePhoto(photo *app.Photo) error
+	UpdatePhoto(photo *app.Photo) error
+	DeletePhoto(photoID, userID uint) error
+	GetPhotoByID(photoID, userID uint) (*app.Photo, error)
+	// FindPhoto membaca foto tanpa memeriksa pemiliknya; hanya untuk moderasi.
+	FindPhoto(photoID uint) (*app.Photo, error)
+	ListPhotos(limit, offset int) ([]app.Photo, error)
+	CountPhotos() (int, error)
+	GetUserPhotos(userID uint, limit, offset int) ([]app.Photo, error)
+	CountUserPhotos(userID uint) (int, error)
+	// CountPhotosByURL menghitung foto semua pengguna yang memakai photoURL.
+	CountPhotosByURL(photoURL string) (int, error)
+	GetUserProfilePhotos(userID uint) ([]app.Photo, error)
+	GetUserProfilePhoto(userID uint) (string, error)
+	// SetProfilePhoto menjadikan foto yang sudah ada sebagai foto profil secara atomik.
+	SetProfilePhoto(photoID, userID uint) (*app.Photo, error)
+}
+
+// TokenRepository menyimpan refresh token, token reset password, token verifikasi email dan
+// daftar pencabutan token akses.
+type TokenRepository interface {
+	CreateRefreshToken(token *app.RefreshToken) error
+	RotateRefreshToken(oldHash string, next *app.RefreshToken) error
+	RevokeRefreshTokenFamily(tokenHash string, userID uint) error
+	RevokeUserRefreshTokens(userID uint) error
+
+	CreatePasswordResetToken(token *app.PasswordResetToken) error
+	// ResetPassword memakai token reset password dan mengganti password pemiliknya secara atomik.
+	ResetPassword(tokenHash string, passwordHash redact.Secret, now time.Time) (uint, error)
+
+	CreateEmailVerificationToken(token *app.EmailVerificationToken) error
+	// VerifyEmail memakai token verifikasi email dan menandai email pemiliknya terverifikasi.
+	VerifyEmail(tokenHash string, now time.Time) error
+
+	RevokeToken(jti string, userID uint, expiresAt time.Time) error
+	GetRevokedTokens(now time.Time) (map[string]time.Time, error)
+	DeleteExpiredRevokedTokens(now time.Time) error
+	SetUserTokenCutoff(userID uint, before time.Time) error
+	GetUserTokenCutoffs(since time.Time) (map[uint]time.Time, error)
+}
+
+// NormalizeEmail mengembalikan email tanpa spasi di awal/akhir dan dalam huruf kecil.
+// Email selalu disimpan dan dicari dalam bentuk ini.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// normalizeUser menormalisasi email dan username sebelum disimpan.
+// Pengguna baru tanpa role menjadi pengguna biasa.
+func normalizeUser(user *app.User) {
+	user.Email = NormalizeEmail(user.Email)
+	user.Username = strings.TrimSpace(user.Username)
+	if user.Role == "" {
+		user.Role = app.RoleUser
+	}
+}
+
+// normalizeUserUpdate menormalisasi email dan username yang akan diubah.
+func normalizeUserUpdate(update *app.UserUpdate) {
+	if update.Email != nil {
+		email := NormalizeEmail(*update.Email)
+		update.Email = &email
+	}
+	if update.Username != nil {
+		username := strings.TrimSpace(*update.Username)
+		update.Username = &username
+	}
+}
+
+// notFound menerjemahkan sql.ErrNoRows menjadi ErrNotFound.
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"backend-api/database"
+	"database/sql"
+	"time"
+)
+
+// sqlTokenRepository adalah TokenRepository yang disimpan di database SQL.
+type sqlTokenRepository struct {
+	sqlStore
+}
+
+// NewSQLTokenRepository membuat TokenRepository di atas koneksi db dengan dialek dialect.
+func NewSQLTokenRepository(db *sql.DB, dialect database.Dialect) TokenRepository {
+	return &sqlTokenRepository{sqlStore{db: db, dialect: dialect}}
+}
+
+// RevokeToken menyimpan jti token akses yang dicabut sampai token tersebut kedaluwarsa.
+func (repo *sqlTokenRepository) RevokeToken(jti string, userID uint, expiresAt time.Time) error {
+	query := `INSERT INTO revoked_tokens (jti, user_id, expires_at) VALUES (?, ?, ?)`
+	_, err := repo.exec(query, jti, userID, expiresAt)
+	if repo.isDuplicateKey(err) {
+		// Token sudah dicabut sebelumnya
+		return nil
+	}
+	return err
+}
+
+// GetRevokedTokens mengembalikan jti dan waktu kedaluwarsa dari token yang dicabut dan belum kedaluwarsa.
+func (repo *sqlTokenRepository) GetRevokedTokens(now time.Time) (map[string]time.Time, error) {
+	rows, err := repo.query(`SELECT jti, expires_at FROM revoked_tokens WHERE expires_at > ?`, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := map[string]time.Time{}
+	for rows.Next() {
+		var jti string
+		var expiresAt time.Time
+		if err := rows.Scan(&jti, &expiresAt); err != nil {
+			return nil, err
+		}
+		tokens[jti] = expiresAt.UTC()
+	}
+	return tokens, rows.Err()
+}
+
+// DeleteExpiredRevokedTokens menghapus token yang dicabut dan sudah kedaluwarsa.
+func (repo *sqlTokenRepository) DeleteExpiredRevokedTokens(now time.Time) error {
+	_, err := repo.exec(`DELETE FROM revoked_tokens WHERE expires_at <= ?`, now)
+	return err
+}
+
+// SetUserTokenCutoff mencabut semua token akses pengguna yang diterbitkan sebelum waktu before.
+func (repo *sqlTokenRepository) SetUserTokenCutoff(userID uint, before time.Time) error {
+	// Batas waktu hanya boleh maju, tidak pernah mundur
+	updateQuery := `UPDATE user_token_cutoffs SET revoked_before = ? WHERE user_id = ? AND revoked_before < ?`
+	for attempt := 0; attempt < 2; attempt++ {
+		result, err := repo.exec(updateQuery, before, userID, before)
+		if err != nil {
+			return err
+		}
+		if affected, err := result.RowsAffected(); err != nil || affected > 0 {
+			return err
+		}
+
+		var exists bool
+		err = repo.queryRow(`SELECT true FROM user_token_cutoffs WHERE user_id = ?`, userID).Scan(&exists)
+		if err == nil {
+			// Batas waktu yang tersimpan sudah sama atau lebih baru
+			return nil
+		}
+		if err != sql.ErrNoRows {
+			return err
+		}
+
+		_, err = repo.exec(`INSERT INTO user_token_cutoffs (user_id, revoked_before) VALUES (?, ?)`, userID, before)
+		if !repo.isDuplicateKey(err) {
+			return err
+		}
+		// Baris baru saja dibuat oleh permintaan lain; coba UPDATE sekali lagi
+	}
+	return nil
+}
+
+// GetUserTokenCutoffs mengembalikan batas waktu pencabutan per pengguna yang lebih baru dari since.
+func (repo *sqlTokenRepository) GetUserTokenCutoffs(since time.Time) (map[uint]time.Time, error) {
+	rows, err := repo.query(`SELECT user_id, revoked_before FROM user_token_cutoffs WHERE revoked_before > ?`, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cutoffs := map[uint]time.Time{}
+	for rows.Next() {
+		var userID uint
+		var revokedBefore time.Time
+		if err := rows.Scan(&userID, &revokedBefore); err != nil {
+			return nil, err
+		}
+		cutoffs[userID] = revokedBefore.UTC()
+	}
+	return cutoffs, rows.Err()
+}
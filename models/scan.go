@@ -0,0 +1,116 @@
+package models
+
+import (
+	"backend-api/app"
+	"database/sql"
+	"time"
+)
+
+// Kolom waktu dipindai langsung ke time.Time (driver MySQL memakai parseTime=true)
+// dan selalu dikembalikan dalam UTC, sama seperti saat disimpan oleh sqlStore.
+
+// userColumns adalah kolom yang dibaca oleh scanUserColumns, sesuai urutannya.
+const userColumns = `id, username, email, password, email_verified_at, role, suspended_at, deleted_at, created_at, updated_at`
+
+// photoColumns adalah kolom yang dibaca oleh scanPhoto, sesuai urutannya.
+const photoColumns = `id, photo_url, user_id, is_profile, created_at, updated_at`
+
+// rowScanner adalah *sql.Row atau *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanUser membaca satu baris users yang dipilih dengan userColumns.
+func scanUser(row *sql.Row) (app.User, error) {
+	user, err := scanUserColumns(row)
+	return user, notFound(err)
+}
+
+// scanUserColumns membaca kolom userColumns diikuti kolom tambahan extra.
+func scanUserColumns(row rowScanner, extra ...interface{}) (app.User, error) {
+	var user app.User
+	var verifiedAt, suspendedAt, deletedAt sql.NullTime
+	dest := []interface{}{&user.ID, &user.Username, &user.Email, &user.Password, &verifiedAt, &user.Role, &suspendedAt, &deletedAt, &user.CreatedAt, &user.UpdatedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return user, err
+	}
+	user.EmailVerifiedAt = nullTime(verifiedAt)
+	user.SuspendedAt = nullTime(suspendedAt)
+	user.DeletedAt = nullTime(deletedAt)
+	user.CreatedAt = user.CreatedAt.UTC()
+	user.UpdatedAt = user.UpdatedAt.UTC()
+	return user, nil
+}
+
+// scanUsers memindai baris-baris users lalu menutup rows.
+func scanUsers(rows *sql.Rows) ([]app.User, error) {
+	defer rows.Close()
+
+	users := []app.User{}
+	for rows.Next() {
+		user, err := scanUserColumns(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// scanPhoto membaca satu baris photos yang dipilih dengan photoColumns.
+func scanPhoto(row rowScanner) (app.Photo, error) {
+	var photo app.Photo
+	if err := row.Scan(&photo.ID, &photo.PhotoURL, &photo.UserID, &photo.IsProfile, &photo.CreatedAt, &photo.UpdatedAt); err != nil {
+		return photo, err
+	}
+	photo.CreatedAt = photo.CreatedAt.UTC()
+	photo.UpdatedAt = photo.UpdatedAt.UTC()
+	return photo, nil
+}
+
+// scanPhotos memindai baris-baris photos lalu menutup rows.
+func scanPhotos(rows *sql.Rows) ([]app.Photo, error) {
+	defer rows.Close()
+
+	photos := []app.Photo{}
+	for rows.Next() {
+		photo, err := scanPhoto(rows)
+		if err != nil {
+			return nil, err
+		}
+		photos = append(photos, photo)
+	}
+	return photos, rows.Err()
+}
+
+// nullTime mengubah kolom waktu yang boleh NULL menjadi pointer waktu UTC.
+func nullTime(value sql.NullTime) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	t := value.Time.UTC()
+	return &t
+}
+
+// utcArgs mengubah argumen query bertipe time.Time dan *time.Time ke UTC agar
+// semua dialek menyimpan waktu yang sama, termasuk kolom TIMESTAMP PostgreSQL
+// yang mengabaikan zona waktu.
+func utcArgs(args []interface{}) []interface{} {
+	converted := make([]interface{}, len(args))
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case time.Time:
+			converted[i] = v.UTC()
+		case *time.Time:
+			if v != nil {
+				t := v.UTC()
+				converted[i] = &t
+			} else {
+				converted[i] = v
+			}
+		default:
+			converted[i] = arg
+		}
+	}
+	return converted
+}
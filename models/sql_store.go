@@ -0,0 +1,80 @@
+package models
+
+import (
+	"backend-api/database"
+	"context"
+	"database/sql"
+)
+
+// sqlStore membungkus koneksi database beserta dialeknya. Query ditulis dengan
+// placeholder "?" dan diterjemahkan oleh dialek sebelum dijalankan; argumen
+// waktu selalu dikirim dalam UTC.
+type sqlStore struct {
+	db      *sql.DB
+	dialect database.Dialect
+}
+
+func (s sqlStore) exec(query string, args ...interface{}) (sql.Result, error) {
+	return s.db.Exec(s.dialect.Rebind(query), utcArgs(args)...)
+}
+
+func (s sqlStore) query(query string, args ...interface{}) (*sql.Rows, error) {
+	return s.db.Query(s.dialect.Rebind(query), utcArgs(args)...)
+}
+
+func (s sqlStore) queryRow(query string, args ...interface{}) *sql.Row {
+	return s.db.QueryRow(s.dialect.Rebind(query), utcArgs(args)...)
+}
+
+// insert menjalankan INSERT dan mengembalikan ID baris baru.
+func (s sqlStore) insert(query string, args ...interface{}) (uint, error) {
+	id, err := database.InsertID(context.Background(), s.db, s.dialect, query, utcArgs(args)...)
+	return uint(id), err
+}
+
+// isDuplicateKey memeriksa apakah err adalah pelanggaran constraint UNIQUE.
+func (s sqlStore) isDuplicateKey(err error) bool {
+	return err != nil && s.dialect.IsUniqueViolation(err)
+}
+
+// withTx menjalankan fn di dalam transaksi; transaksi di-rollback jika fn gagal.
+func (s sqlStore) withTx(fn func(tx sqlTx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if err := fn(sqlTx{tx: tx, dialect: s.dialect}); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// sqlTx adalah transaksi yang menerjemahkan query seperti sqlStore.
+type sqlTx struct {
+	tx      *sql.Tx
+	dialect database.Dialect
+}
+
+func (t sqlTx) exec(query string, args ...interface{}) (sql.Result, error) {
+	return t.tx.Exec(t.dialect.Rebind(query), utcArgs(args)...)
+}
+
+func (t sqlTx) query(query string, args ...interface{}) (*sql.Rows, error) {
+	return t.tx.Query(t.dialect.Rebind(query), utcArgs(args)...)
+}
+
+func (t sqlTx) queryRow(query string, args ...interface{}) *sql.Row {
+	return t.tx.QueryRow(t.dialect.Rebind(query), utcArgs(args)...)
+}
+
+func (t sqlTx) insert(query string, args ...interface{}) (uint, error) {
+	id, err := database.InsertID(context.Background(), t.tx, t.dialect, query, utcArgs(args)...)
+	return uint(id), err
+}
+
+// forUpdate mengembalikan akhiran SELECT untuk mengunci baris, jika didukung dialek.
+func (t sqlTx) forUpdate() string {
+	return t.dialect.ForUpdate()
+}
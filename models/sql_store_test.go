@@ -0,0 +1,190 @@
+package models
+
+import (
+	"backend-api/app"
+	"backend-api/config"
+	"backend-api/database"
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// openSQLite membuka database SQLite baru di direktori sementara dan menjalankan
+// seluruh migrasi di atasnya.
+func openSQLite(t *testing.T) (*sql.DB, database.Dialect) {
+	t.Helper()
+
+	dialect := database.SQLite{}
+	dsn, err := dialect.DSN(config.Database{Name: filepath.Join(t.TempDir(), "test.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open(dialect.DriverName(), dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := database.NewMigrator(db, dialect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("running migrations: %v", err)
+	}
+	return db, dialect
+}
+
+// expectUTC memeriksa bahwa got adalah waktu want yang dikembalikan dalam UTC.
+func expectUTC(t *testing.T, name string, got *time.Time, want time.Time) {
+	t.Helper()
+	if got == nil {
+		t.Errorf("%s = nil, want %s", name, want)
+		return
+	}
+	if got.Location() != time.UTC {
+		t.Errorf("%s location = %s, want UTC", name, got.Location())
+	}
+	if !got.Equal(want) {
+		t.Errorf("%s = %s, want %s", name, got, want)
+	}
+}
+
+func TestSQLUserRepositoryRoundTrip(t *testing.T) {
+	db, dialect := openSQLite(t)
+	users := NewSQLUserRepository(db, dialect)
+
+	// Waktu sengaja dibuat di zona selain UTC untuk memastikan konversinya
+	jakarta := time.FixedZone("WIB", 7*60*60)
+	createdAt := time.Date(2024, 3, 1, 9, 30, 15, 123456000, jakarta)
+	user := app.User{Username: "Alice", Email: " Alice@Example.com ", Password: "hash", Role: app.RoleUser, CreatedAt: createdAt, UpdatedAt: createdAt}
+	if err := users.CreateUser(&user); err != nil {
+		t.Fatal(err)
+	}
+
+	verifiedAt := createdAt.Add(time.Hour)
+	if err := users.MarkEmailVerified(user.ID, "alice@example.com", verifiedAt); err != nil {
+		t.Fatal(err)
+	}
+	deletedAt := createdAt.Add(2 * time.Hour)
+	if err := users.DeleteUser(user.ID, deletedAt); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := users.GetUserByID(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Username != "Alice" || got.Email != "alice@example.com" || got.Password.Reveal() != "hash" || got.Role != app.RoleUser {
+		t.Errorf("user = %+v, want Alice with a normalized email", got)
+	}
+	expectUTC(t, "CreatedAt", &got.CreatedAt, createdAt)
+	expectUTC(t, "UpdatedAt", &got.UpdatedAt, deletedAt)
+	expectUTC(t, "EmailVerifiedAt", got.EmailVerifiedAt, verifiedAt)
+	expectUTC(t, "DeletedAt", got.DeletedAt, deletedAt)
+	if got.SuspendedAt != nil {
+		t.Errorf("SuspendedAt = %s, want nil", got.SuspendedAt)
+	}
+
+	if _, err := users.GetUserByEmail("ALICE@example.com"); err != nil {
+		t.Errorf("GetUserByEmail with different case: %v", err)
+	}
+	duplicate := app.User{Username: "alice", Email: "bob@example.com", Password: "hash", Role: app.RoleUser, CreatedAt: createdAt, UpdatedAt: createdAt}
+	if err := users.CreateUser(&duplicate); err != ErrUsernameTaken {
+		t.Errorf("CreateUser with a taken username = %v, want ErrUsernameTaken", err)
+	}
+}
+
+func TestSQLPhotoRepositoryRoundTrip(t *testing.T) {
+	db, dialect := openSQLite(t)
+	users := NewSQLUserRepository(db, dialect)
+	photos := NewSQLPhotoRepository(db, dialect)
+
+	now := time.Now().In(time.FixedZone("WIB", 7*60*60)).Truncate(time.Microsecond)
+	user := app.User{Username: "alice", Email: "alice@example.com", Password: "hash", Role: app.RoleUser, CreatedAt: now, UpdatedAt: now}
+	if err := users.CreateUser(&user); err != nil {
+		t.Fatal(err)
+	}
+
+	photo := app.Photo{PhotoURL: "http://example.com/a.jpg", UserID: user.ID, CreatedAt: now, UpdatedAt: now}
+	if err := photos.CreatePhoto(&photo); err != nil {
+		t.Fatal(err)
+	}
+	profile := app.Photo{PhotoURL: "http://example.com/b.jpg", UserID: user.ID, IsProfile: true, CreatedAt: now.Add(time.Second), UpdatedAt: now.Add(time.Second)}
+	if err := photos.CreateProfilePhoto(&profile); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := photos.GetPhotoByID(photo.ID, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.PhotoURL != photo.PhotoURL || got.UserID != user.ID || got.IsProfile {
+		t.Errorf("photo = %+v, want %+v", got, photo)
+	}
+	expectUTC(t, "CreatedAt", &got.CreatedAt, now)
+	expectUTC(t, "UpdatedAt", &got.UpdatedAt, now)
+
+	if _, err := photos.GetPhotoByID(photo.ID, user.ID+1); err != ErrNotFound {
+		t.Errorf("GetPhotoByID of another user = %v, want ErrNotFound", err)
+	}
+
+	withPhotos, err := users.GetUserWithPhotos(user.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(withPhotos.Photos) != 2 || withPhotos.Photos[0].ID != profile.ID || !withPhotos.Photos[0].IsProfile {
+		t.Fatalf("photos = %+v, want the profile photo first", withPhotos.Photos)
+	}
+	for _, p := range withPhotos.Photos {
+		if p.CreatedAt.Location() != time.UTC || p.UpdatedAt.Location() != time.UTC {
+			t.Errorf("photo %d times are not in UTC: %s, %s", p.ID, p.CreatedAt, p.UpdatedAt)
+		}
+	}
+}
+
+func TestSQLVerifyEmail(t *testing.T) {
+	db, dialect := openSQLite(t)
+	users := NewSQLUserRepository(db, dialect)
+	tokens := NewSQLTokenRepository(db, dialect)
+
+	now := time.Now().Truncate(time.Microsecond)
+	user := app.User{Username: "alice", Email: "alice@example.com", Password: "hash", Role: app.RoleUser, CreatedAt: now, UpdatedAt: now}
+	if err := users.CreateUser(&user); err != nil {
+		t.Fatal(err)
+	}
+	token := app.EmailVerificationToken{UserID: user.ID, Email: user.Email, TokenHash: "hash-a", ExpiresAt: now.Add(time.Hour), CreatedAt: now}
+	if err := tokens.CreateEmailVerificationToken(&token); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tokens.VerifyEmail("unknown", now); err != ErrEmailVerificationTokenInvalid {
+		t.Errorf("VerifyEmail with an unknown token = %v, want ErrEmailVerificationTokenInvalid", err)
+	}
+	if err := tokens.VerifyEmail("hash-a", token.ExpiresAt); err != ErrEmailVerificationTokenInvalid {
+		t.Errorf("VerifyEmail with an expired token = %v, want ErrEmailVerificationTokenInvalid", err)
+	}
+
+	// Memakai token yang sama lagi tidak mengubah waktu verifikasi
+	verifiedAt := now.Add(time.Minute)
+	if err := tokens.VerifyEmail("hash-a", verifiedAt); err != nil {
+		t.Fatal(err)
+	}
+	if err := tokens.VerifyEmail("hash-a", verifiedAt.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	got, err := users.GetUserByID(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectUTC(t, "EmailVerifiedAt", got.EmailVerifiedAt, verifiedAt)
+
+	if _, err := db.Exec(`UPDATE users SET email = 'alice@example.org' WHERE id = ?`, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := tokens.VerifyEmail("hash-a", verifiedAt); err != ErrEmailVerificationTokenInvalid {
+		t.Errorf("VerifyEmail after the email changed = %v, want ErrEmailVerificationTokenInvalid", err)
+	}
+}
@@ -3,57 +3,251 @@ package models
 import (
 	"backend-api/app"
 	"backend-api/database"
-	"backend-api/helpers"
+	"database/sql"
+	"strings"
 	"time"
 )
 
-func CreateUser(user *app.User) error {
-	query := `INSERT INTO users (username, password, email, created_at, updated_at) VALUES (?, ?, ?, ?, ?)`
-	result, err := database.DB.Exec(query, user.Username, user.Password, user.Email, user.CreatedAt, user.UpdatedAt)
-	if err != nil {
-		return err
+// sqlUserRepository adalah UserRepository yang disimpan di database SQL.
+type sqlUserRepository struct {
+	sqlStore
+}
+
+// NewSQLUserRepository membuat UserRepository di atas koneksi db dengan dialek dialect.
+func NewSQLUserRepository(db *sql.DB, dialect database.Dialect) UserRepository {
+	return &sqlUserRepository{sqlStore{db: db, dialect: dialect}}
+}
+
+// CreateUser menyimpan pengguna baru dengan email dan username yang sudah dinormalisasi.
+func (repo *sqlUserRepository) CreateUser(user *app.User) error {
+	normalizeUser(user)
+	query := `INSERT INTO users (username, password, email, role, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`
+	id, err := repo.insert(query, user.Username, user.Password.Reveal(), user.Email, user.Role, user.CreatedAt, user.UpdatedAt)
+	if repo.isDuplicateKey(err) {
+		return repo.duplicateUserError(user, 0)
 	}
-	lastInsertID, err := result.LastInsertId()
 	if err != nil {
 		return err
 	}
-	user.ID = uint(lastInsertID)
+	user.ID = id
 	return nil
 }
 
-func GetUserByEmail(email string) (app.User, error) {
-	var user app.User
-	var createdAt, updatedAt string
-	query := `SELECT id, username, email, password, created_at, updated_at FROM users WHERE email = ?`
-	err := database.DB.QueryRow(query, email).Scan(&user.ID, &user.Username, &user.Email, &user.Password, &createdAt, &updatedAt)
+func (repo *sqlUserRepository) GetUserByEmail(email string) (app.User, error) {
+	return scanUser(repo.queryRow(`SELECT `+userColumns+` FROM users WHERE email = ?`, NormalizeEmail(email)))
+}
+
+func (repo *sqlUserRepository) GetUserByID(userID uint) (app.User, error) {
+	return scanUser(repo.queryRow(`SELECT `+userColumns+` FROM users WHERE id = ?`, userID))
+}
+
+// GetUserWithPhotos membaca pengguna beserta fotonya, terbaru lebih dulu, dalam satu
+// query LEFT JOIN. Jika profileOnly, hanya foto profil yang dimuat.
+func (repo *sqlUserRepository) GetUserWithPhotos(userID uint, profileOnly bool) (app.User, error) {
+	join := `LEFT JOIN photos p ON p.user_id = u.id`
+	if profileOnly {
+		join += ` AND p.is_profile = true`
+	}
+	query := `SELECT u.id, u.username, u.email, u.password, u.email_verified_at, u.role, u.suspended_at, u.deleted_at, u.created_at, u.updated_at,
+		p.id, p.photo_url, p.is_profile, p.created_at, p.updated_at
+		FROM users u ` + join + ` WHERE u.id = ? ORDER BY p.created_at DESC, p.id DESC`
+
+	rows, err := repo.query(query, userID)
 	if err != nil {
-		return user, err
+		return app.User{}, err
 	}
-	user.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAt)
-	user.UpdatedAt, _ = time.Parse("2006-01-02 15:04:05", updatedAt)
-	return user, nil
-}
+	defer rows.Close()
 
-func UpdateUser(userID string, user *app.User) error {
-	// Hash the password if it's provided
-	if user.Password != "" {
-		hashedPassword, err := helpers.HashPassword(user.Password)
+	var user app.User
+	found := false
+	for rows.Next() {
+		var photoID sql.NullInt64
+		var photoURL sql.NullString
+		var isProfile sql.NullBool
+		var photoCreatedAt, photoUpdatedAt sql.NullTime
+		row, err := scanUserColumns(rows, &photoID, &photoURL, &isProfile, &photoCreatedAt, &photoUpdatedAt)
 		if err != nil {
-			return err
+			return app.User{}, err
+		}
+
+		// Kolom pengguna sama di setiap baris, cukup diambil dari baris pertama
+		if !found {
+			found = true
+			user = row
+			user.Photos = []app.Photo{}
 		}
-		user.Password = hashedPassword
+
+		// Pengguna tanpa foto menghasilkan satu baris dengan kolom foto NULL
+		if !photoID.Valid {
+			continue
+		}
+		photo := app.Photo{ID: uint(photoID.Int64), PhotoURL: photoURL.String, UserID: user.ID, IsProfile: isProfile.Bool}
+		photo.CreatedAt = photoCreatedAt.Time.UTC()
+		photo.UpdatedAt = photoUpdatedAt.Time.UTC()
+		user.Photos = append(user.Photos, photo)
+	}
+	if err := rows.Err(); err != nil {
+		return app.User{}, err
 	}
+	if !found {
+		return app.User{}, ErrNotFound
+	}
+	return user, nil
+}
+
+// UpdateUser hanya menyimpan field update yang tidak nil. Jika email berubah,
+// status verifikasi email dihapus.
+func (repo *sqlUserRepository) UpdateUser(userID uint, update app.UserUpdate) error {
+	normalizeUserUpdate(&update)
 
-	// Prepare the SQL query
-	query := `UPDATE users SET username = ?, email = ?, password = ?, updated_at = ? WHERE id = ?`
+	var sets []string
+	var args []interface{}
+	if update.Email != nil {
+		// email_verified_at harus di-set sebelum email karena MySQL mengevaluasi
+		// SET dari kiri ke kanan dengan nilai yang sudah diperbarui
+		sets = append(sets, "email_verified_at = CASE WHEN email = ? THEN email_verified_at ELSE NULL END", "email = ?")
+		args = append(args, *update.Email, *update.Email)
+	}
+	if update.Username != nil {
+		sets = append(sets, "username = ?")
+		args = append(args, *update.Username)
+	}
+	if update.Password != nil {
+		sets = append(sets, "password = ?")
+		args = append(args, update.Password.Reveal())
+	}
+	sets = append(sets, "updated_at = ?")
+	args = append(args, update.UpdatedAt, userID)
 
 	// Execute the query
-	_, err := database.DB.Exec(query, user.Username, user.Email, user.Password, user.UpdatedAt, userID)
+	_, err := repo.exec(`UPDATE users SET `+strings.Join(sets, ", ")+` WHERE id = ?`, args...)
+	if repo.isDuplicateKey(err) {
+		var changed app.User
+		if update.Email != nil {
+			changed.Email = *update.Email
+		}
+		if update.Username != nil {
+			changed.Username = *update.Username
+		}
+		return repo.duplicateUserError(&changed, userID)
+	}
+	return err
+}
+
+// ListUsers mengembalikan pengguna, terbaru lebih dulu, dengan paginasi.
+func (repo *sqlUserRepository) ListUsers(limit, offset int) ([]app.User, error) {
+	rows, err := repo.query(`SELECT `+userColumns+` FROM users ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?`, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return scanUsers(rows)
+}
+
+// CountUsers mengembalikan jumlah seluruh pengguna.
+func (repo *sqlUserRepository) CountUsers() (int, error) {
+	var total int
+	err := repo.queryRow(`SELECT COUNT(*) FROM users`).Scan(&total)
+	return total, err
+}
+
+// SetUserRole mengganti role pengguna.
+func (repo *sqlUserRepository) SetUserRole(userID uint, role app.Role) error {
+	_, err := repo.exec(`UPDATE users SET role = ?, updated_at = ? WHERE id = ?`, role, time.Now(), userID)
+	return err
+}
+
+// SetUserSuspended menangguhkan pengguna sejak at, atau mencabut penangguhan jika at nil.
+func (repo *sqlUserRepository) SetUserSuspended(userID uint, at *time.Time) error {
+	_, err := repo.exec(`UPDATE users SET suspended_at = ?, updated_at = ? WHERE id = ?`, at, time.Now(), userID)
 	return err
 }
 
-func DeleteUser(userID string) error {
-	query := `DELETE FROM users WHERE id = ?`
-	_, err := database.DB.Exec(query, userID)
+// MarkEmailVerified menandai email pengguna sebagai terverifikasi, hanya jika
+// email pengguna masih sama dengan email yang diverifikasi.
+func (repo *sqlUserRepository) MarkEmailVerified(userID uint, email string, at time.Time) error {
+	query := `UPDATE users SET email_verified_at = ? WHERE id = ? AND email = ? AND email_verified_at IS NULL`
+	_, err := repo.exec(query, at, userID, email)
 	return err
 }
+
+// DeleteUser menandai pengguna terhapus sejak at. Baris dan fotonya tetap ada
+// sampai PurgeUser dipanggil, sehingga pengguna masih dapat dipulihkan.
+func (repo *sqlUserRepository) DeleteUser(userID uint, at time.Time) error {
+	query := `UPDATE users SET deleted_at = ?, updated_at = ? WHERE id = ? AND deleted_at IS NULL`
+	_, err := repo.exec(query, at, at, userID)
+	return err
+}
+
+// RestoreUser membatalkan penghapusan pengguna.
+func (repo *sqlUserRepository) RestoreUser(userID uint) error {
+	_, err := repo.exec(`UPDATE users SET deleted_at = NULL, updated_at = ? WHERE id = ?`, time.Now(), userID)
+	return err
+}
+
+// DeletedUsersBefore mengembalikan paling banyak limit ID pengguna yang dihapus sebelum before.
+func (repo *sqlUserRepository) DeletedUsersBefore(before time.Time, limit int) ([]uint, error) {
+	rows, err := repo.query(`SELECT id FROM users WHERE deleted_at IS NOT NULL AND deleted_at < ? ORDER BY deleted_at LIMIT ?`, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []uint{}
+	for rows.Next() {
+		var id uint
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// PurgeUser menghapus permanen pengguna yang dihapus sebelum before, beserta
+// foto dan tokennya (ON DELETE CASCADE). Foto yang ikut terhapus dikembalikan
+// agar berkasnya dapat dihapus dari storage. Mengembalikan ErrNotFound jika
+// pengguna tidak ada, belum dihapus, atau sudah dipulihkan.
+func (repo *sqlUserRepository) PurgeUser(userID uint, before time.Time) ([]app.Photo, error) {
+	var photos []app.Photo
+	err := repo.withTx(func(tx sqlTx) error {
+		var id uint
+		query := `SELECT id FROM users WHERE id = ? AND deleted_at IS NOT NULL AND deleted_at < ?` + tx.forUpdate()
+		if err := tx.queryRow(query, userID, before).Scan(&id); err != nil {
+			return notFound(err)
+		}
+
+		rows, err := tx.query(`SELECT `+photoColumns+` FROM photos WHERE user_id = ?`, userID)
+		if err != nil {
+			return err
+		}
+		if photos, err = scanPhotos(rows); err != nil {
+			return err
+		}
+
+		_, err = tx.exec(`DELETE FROM users WHERE id = ?`, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return photos, nil
+}
+
+// duplicateUserError menentukan constraint UNIQUE mana yang dilanggar oleh user.
+// excludeID adalah ID pengguna yang sedang diperbarui (0 saat membuat pengguna).
+func (repo *sqlUserRepository) duplicateUserError(user *app.User, excludeID uint) error {
+	var count int
+	if user.Email != "" {
+		err := repo.queryRow(`SELECT COUNT(*) FROM users WHERE email = ? AND id <> ?`, user.Email, excludeID).Scan(&count)
+		if err == nil && count > 0 {
+			return ErrEmailTaken
+		}
+	}
+	if user.Username != "" {
+		err := repo.queryRow(`SELECT COUNT(*) FROM users WHERE LOWER(username) = LOWER(?) AND id <> ?`, user.Username, excludeID).Scan(&count)
+		if err == nil && count > 0 {
+			return ErrUsernameTaken
+		}
+	}
+	return ErrDuplicate
+}
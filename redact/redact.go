@@ -0,0 +1,62 @@
+// Package redact keeps secrets and personal data out of logs and responses.
+package redact
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Placeholder replaces redacted values
+const Placeholder = "[REDACTED]"
+
+// Secret is a string that is never printed or serialized. fmt verbs, JSON and
+// text encoding all produce Placeholder; use Reveal to read the value.
+type Secret string
+
+// Reveal returns the secret value
+func (s Secret) Reveal() string {
+	return string(s)
+}
+
+func (s Secret) String() string {
+	return Placeholder
+}
+
+func (s Secret) GoString() string {
+	return `redact.Secret("` + Placeholder + `")`
+}
+
+// Format makes every fmt verb, including %s, %q, %x and %#v, print Placeholder
+func (s Secret) Format(f fmt.State, verb rune) {
+	if verb == 'v' && f.Flag('#') {
+		fmt.Fprint(f, s.GoString())
+		return
+	}
+	fmt.Fprint(f, Placeholder)
+}
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + Placeholder + `"`), nil
+}
+
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(Placeholder), nil
+}
+
+// Email masks the local part of an email address for logging, e.g. "j***@example.com"
+func Email(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		return Placeholder
+	}
+	return email[:1] + "***" + email[at:]
+}
+
+// Token shows only the first characters of a token for logging
+func Token(token string) string {
+	const visible = 6
+	if len(token) <= visible*2 {
+		return Placeholder
+	}
+	return token[:visible] + "..." + Placeholder
+}
@@ -0,0 +1,135 @@
+package revocation
+
+import (
+	"backend-api/models"
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// List is the server-side access token revocation list.
+// Revocations are stored in the database and mirrored in memory so that
+// checking a token on every request does not hit the database. The cache is
+// reloaded periodically to pick up revocations made by other instances.
+type List struct {
+	store models.TokenRepository
+	// accessTokenTTL is the lifetime of access tokens; older cutoffs are not loaded
+	accessTokenTTL time.Duration
+	mu             sync.RWMutex
+	tokens         map[string]time.Time // jti -> token expiry
+	cutoffs        map[uint]time.Time   // user ID -> tokens issued before are revoked
+}
+
+// NewList returns an empty revocation list backed by store for access tokens
+// that live for accessTokenTTL
+func NewList(store models.TokenRepository, accessTokenTTL time.Duration) *List {
+	return &List{store: store, accessTokenTTL: accessTokenTTL, tokens: map[string]time.Time{}, cutoffs: map[uint]time.Time{}}
+}
+
+// IsRevoked reports whether the access token jti of userID issued at issuedAt has been revoked
+func (l *List) IsRevoked(jti string, userID uint, issuedAt time.Time) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if _, ok := l.tokens[jti]; ok {
+		return true
+	}
+	cutoff, ok := l.cutoffs[userID]
+	return ok && issuedAt.Before(cutoff)
+}
+
+// RevokeToken revokes a single access token until it expires
+func (l *List) RevokeToken(jti string, userID uint, expiresAt time.Time) error {
+	if err := l.store.RevokeToken(jti, userID, expiresAt); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	l.tokens[jti] = expiresAt
+	l.mu.Unlock()
+	return nil
+}
+
+// RevokeAllForUser revokes every access and refresh token issued to userID so far.
+// Access tokens carry their issue time in microseconds, so a token issued right
+// after the revocation, e.g. by logging in again, stays valid.
+func (l *List) RevokeAllForUser(userID uint) error {
+	cutoff := time.Now().Truncate(time.Microsecond)
+	if err := l.store.SetUserTokenCutoff(userID, cutoff); err != nil {
+		return err
+	}
+	if err := l.store.RevokeUserRefreshTokens(userID); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	if cutoff.After(l.cutoffs[userID]) {
+		l.cutoffs[userID] = cutoff
+	}
+	l.mu.Unlock()
+	return nil
+}
+
+// Reload refreshes the cache with the revocations stored in the database.
+// Cutoffs older than the access token lifetime are skipped since every token
+// they would reject has already expired.
+func (l *List) Reload() error {
+	now := time.Now()
+	tokens, err := l.store.GetRevokedTokens(now)
+	if err != nil {
+		return err
+	}
+	cutoffs, err := l.store.GetUserTokenCutoffs(now.Add(-l.accessTokenTTL))
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Keep revocations added locally while the database was being read
+	for jti, expiresAt := range l.tokens {
+		if _, ok := tokens[jti]; !ok && expiresAt.After(now) {
+			tokens[jti] = expiresAt
+		}
+	}
+	for userID, cutoff := range l.cutoffs {
+		if cutoff.After(cutoffs[userID]) {
+			cutoffs[userID] = cutoff
+		}
+	}
+	l.tokens = tokens
+	l.cutoffs = cutoffs
+	return nil
+}
+
+// Start reloads the cache every interval and removes expired revocations from
+// the database until ctx is done. The returned channel is closed once the
+// worker has stopped.
+func (l *List) Start(ctx context.Context, interval time.Duration) <-chan struct{} {
+	if err := l.Reload(); err != nil {
+		log.Printf("Error loading token revocation list: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if err := l.store.DeleteExpiredRevokedTokens(time.Now()); err != nil {
+				log.Printf("Error deleting expired revoked tokens: %v", err)
+			}
+			if err := l.Reload(); err != nil {
+				log.Printf("Error reloading token revocation list: %v", err)
+			}
+		}
+	}()
+	return done
+}
@@ -1,31 +1,99 @@
 package router
 
 import (
+	"backend-api/apierror"
+	"backend-api/app"
 	"backend-api/controllers"
 	"backend-api/middlewares"
+	"backend-api/storage"
+	"net/http"
 
 	"github.com/gorilla/mux"
 )
 
-func NewRouter() *mux.Router {
+// NewRouter registers the routes of s
+func NewRouter(s *controllers.Server) *mux.Router {
 	r := mux.NewRouter()
 
-	// User routes
-	r.HandleFunc("/users/register", controllers.RegisterUser).Methods("POST")
-	r.HandleFunc("/users/login", controllers.LoginUser).Methods("POST")
+	// Every response carries a request ID; errors are RFC 7807 problem details
+	r.Use(middlewares.RequestID)
+	r.Use(middlewares.ResolveClientIP(s.Config.Server.TrustProxy))
+	r.NotFoundHandler = middlewares.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apierror.Write(w, r, apierror.ErrRouteNotFound)
+	}))
+	r.MethodNotAllowedHandler = middlewares.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apierror.Write(w, r, apierror.ErrMethodNotAllowed)
+	}))
 
-	// Create a subrouter for protected user routes
+	// Public routes, rate limited per client IP. Each route group has its own
+	// limit, configurable with RATE_LIMIT_<GROUP>.
+	limits := s.Config.RateLimits
+	public := r.NewRoute().Subrouter()
+	public.Use(middlewares.RateLimit(middlewares.RateLimiterFor("public", limits.Public)))
+	public.HandleFunc("/.well-known/jwks.json", controllers.GetJWKS).Methods("GET") // Public keys for verifying access tokens
+	public.HandleFunc("/users/{userId:[0-9]+}", s.GetUser).Methods("GET")
+
+	// Routes that create accounts, check passwords or send emails get a tighter limit
+	auth := r.NewRoute().Subrouter()
+	auth.Use(middlewares.RateLimit(middlewares.RateLimiterFor("auth", limits.Auth)))
+	auth.HandleFunc("/users/register", s.RegisterUser).Methods("POST")
+	auth.HandleFunc("/users/login", s.LoginUser).Methods("POST")
+	auth.HandleFunc("/users/token/refresh", s.RefreshToken).Methods("POST")
+	auth.HandleFunc("/users/verify", s.VerifyEmail).Methods("GET")
+	auth.HandleFunc("/users/verify/resend", s.ResendVerification).Methods("POST")
+	auth.HandleFunc("/users/password/forgot", s.ForgotPassword).Methods("POST")
+	auth.HandleFunc("/users/password/reset", s.ResetPassword).Methods("POST")
+	auth.HandleFunc("/users/restore", s.RestoreAccount).Methods("POST")
+
+	// Create a subrouter for protected user routes, rate limited per user
 	userRouter := r.PathPrefix("/users").Subrouter()
-	userRouter.Use(middlewares.JWTAuth)
-	userRouter.HandleFunc("/{userId}", controllers.UpdateUser).Methods("PUT")
-	userRouter.HandleFunc("/{userId}", controllers.DeleteUser).Methods("DELETE")
+	userRouter.Use(middlewares.JWTAuth(s.Revocations, s.Accounts))
+	userRouter.Use(middlewares.RateLimit(middlewares.RateLimiterFor("users", limits.Users)))
+	userRouter.HandleFunc("/logout", s.Logout).Methods("POST")
+	userRouter.HandleFunc("/logout/all", s.LogoutAll).Methods("POST")
+	userRouter.HandleFunc("/me", s.GetCurrentUser).Methods("GET")
+	userRouter.HandleFunc("/{userId}", s.UpdateUser).Methods("PATCH", "PUT")
+	userRouter.HandleFunc("/{userId}", s.DeleteUser).Methods("DELETE")
 
 	// Protected Photo routes with JWT
 	api := r.PathPrefix("/api").Subrouter()
-	api.Use(middlewares.JWTAuth)
-	api.HandleFunc("/photos/profile", controllers.GetProfilePhoto).Methods("GET")  // Mendapatkan foto profil
-	api.HandleFunc("/photos/profile", controllers.SetProfilePhoto).Methods("POST") // Mengatur sebuah foto sebagai foto profil
-	api.HandleFunc("/photos/{photoId}", controllers.DeletePhoto).Methods("DELETE") // Menghapus sebuah foto
+	api.Use(middlewares.JWTAuth(s.Revocations, s.Accounts))
+	if s.Config.Accounts.RequireVerifiedEmail {
+		api.Use(middlewares.RequireVerifiedEmail)
+	}
+	api.Use(middlewares.RateLimit(middlewares.RateLimiterFor("api", limits.API)))
+	uploads := middlewares.RateLimit(middlewares.RateLimiterFor("uploads", limits.Uploads))
+	api.HandleFunc("/photos/profile", s.GetProfilePhoto).Methods("GET")                    // Mendapatkan foto profil
+	api.HandleFunc("/photos/profile", s.SetProfilePhoto).Methods("POST")                   // Mengatur sebuah foto sebagai foto profil
+	api.Handle("/photos/upload", uploads(http.HandlerFunc(s.UploadPhoto))).Methods("POST") // Mengunggah berkas foto
+	api.HandleFunc("/photos", s.ListPhotos).Methods("GET")                                 // Mendapatkan semua foto pengguna
+	api.HandleFunc("/photos", s.CreatePhoto).Methods("POST")                               // Menambahkan foto ke galeri
+	api.HandleFunc("/photos/{photoId}", s.GetPhoto).Methods("GET")                         // Mendapatkan sebuah foto
+	api.HandleFunc("/photos/{photoId}", s.UpdatePhoto).Methods("PUT")                      // Memperbarui sebuah foto
+	api.HandleFunc("/photos/{photoId}", s.DeletePhoto).Methods("DELETE")                   // Menghapus sebuah foto
+	api.HandleFunc("/photos/{photoId}/profile", s.PromoteProfilePhoto).Methods("POST")     // Menjadikan sebuah foto sebagai foto profil
+
+	// Moderation routes; each route requires the permissions of a staff role
+	admin := r.PathPrefix("/admin").Subrouter()
+	admin.Use(middlewares.JWTAuth(s.Revocations, s.Accounts))
+	admin.Use(middlewares.RateLimit(middlewares.RateLimiterFor("admin", limits.Admin)))
+	requires := func(handler http.HandlerFunc, perms ...app.Permission) http.Handler {
+		return middlewares.RequirePermissions(perms...)(handler)
+	}
+	admin.Handle("/users", requires(s.AdminListUsers, app.PermUsersRead)).Methods("GET")
+	admin.Handle("/users/{userId:[0-9]+}", requires(s.AdminDeleteUser, app.PermUsersWrite)).Methods("DELETE")
+	admin.Handle("/users/{userId:[0-9]+}/suspend", requires(s.AdminSuspendUser, app.PermUsersWrite)).Methods("POST")
+	admin.Handle("/users/{userId:[0-9]+}/suspend", requires(s.AdminUnsuspendUser, app.PermUsersWrite)).Methods("DELETE")
+	admin.Handle("/users/{userId:[0-9]+}/restore", requires(s.AdminRestoreUser, app.PermUsersWrite)).Methods("POST")
+	admin.Handle("/users/{userId:[0-9]+}/role", requires(s.AdminSetUserRole, app.PermUsersRoles)).Methods("PUT")
+	admin.Handle("/photos", requires(s.AdminListPhotos, app.PermPhotosRead)).Methods("GET")
+	admin.Handle("/photos/{photoId:[0-9]+}", requires(s.AdminDeletePhoto, app.PermPhotosWrite)).Methods("DELETE")
+
+	// Serve uploaded files when they are stored on the local filesystem
+	if local, ok := s.Storage.(*storage.LocalStorage); ok && local.BasePath() != "" {
+		prefix := local.BasePath() + "/"
+		r.PathPrefix(prefix).Handler(http.StripPrefix(prefix, local.Handler())).Methods("GET")
+	}
 
 	return r
 }
@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// newTestS3Storage connects to the S3-compatible endpoint named by
+// S3_TEST_ENDPOINT, skipping the test when it is not set. The bucket in
+// S3_TEST_BUCKET must already exist.
+func newTestS3Storage(t *testing.T) *S3Storage {
+	t.Helper()
+
+	cfg := S3Config{
+		Endpoint:  os.Getenv("S3_TEST_ENDPOINT"),
+		AccessKey: os.Getenv("S3_TEST_ACCESS_KEY"),
+		SecretKey: os.Getenv("S3_TEST_SECRET_KEY"),
+		Bucket:    os.Getenv("S3_TEST_BUCKET"),
+		Region:    os.Getenv("S3_TEST_REGION"),
+		UseSSL:    os.Getenv("S3_TEST_USE_SSL") == "true",
+	}
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		t.Skip("set S3_TEST_ENDPOINT and S3_TEST_BUCKET to run the S3 integration tests")
+	}
+
+	s, err := NewS3Storage(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestS3Storage(t *testing.T) {
+	s := newTestS3Storage(t)
+	ctx := context.Background()
+
+	key, err := NewKey(PhotoKeyPrefix(1), ".png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := "\x89PNG\r\n\x1a\nnot really a picture"
+
+	// size -1 exercises the multipart upload used for bodies of unknown length
+	url, err := s.Put(ctx, key, strings.NewReader(content), -1, "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Delete(context.Background(), key) })
+
+	got, ok := s.KeyFromURL(url)
+	if !ok || got != key {
+		t.Fatalf("KeyFromURL(%q) = %q, %v; want %q", url, got, ok, key)
+	}
+
+	object, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(object)
+	object.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != content {
+		t.Errorf("stored object = %q, want %q", data, content)
+	}
+	info, err := s.client.StatObject(ctx, s.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ContentType != "image/png" {
+		t.Errorf("content type = %q, want image/png", info.ContentType)
+	}
+
+	if err := s.Delete(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.client.StatObject(ctx, s.bucket, key, minio.StatObjectOptions{})
+	if minio.ToErrorResponse(err).StatusCode != http.StatusNotFound {
+		t.Errorf("StatObject after Delete: %v, want a not found error", err)
+	}
+
+	// Deleting a missing object succeeds, so a retried delete does not fail
+	if err := s.Delete(ctx, key); err != nil {
+		t.Errorf("deleting a missing object: %v", err)
+	}
+}
+
+func TestS3StorageKeyFromURL(t *testing.T) {
+	s := newTestS3Storage(t)
+
+	tests := []struct {
+		url string
+		ok  bool
+	}{
+		{s.publicURL + "/photos/1/a.png", true},
+		{s.publicURL + "/", false},
+		{s.publicURL, false},
+		{s.publicURL + "-other/photos/1/a.png", false},
+		{"http://example.com/photos/1/a.png", false},
+	}
+	for _, tt := range tests {
+		key, ok := s.KeyFromURL(tt.url)
+		if ok != tt.ok {
+			t.Errorf("KeyFromURL(%q) = %q, %v; want ok %v", tt.url, key, ok, tt.ok)
+		}
+		if ok && key != "photos/1/a.png" {
+			t.Errorf("KeyFromURL(%q) = %q, want photos/1/a.png", tt.url, key)
+		}
+	}
+}
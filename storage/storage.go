@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"backend-api/config"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Storage stores uploaded files and returns the public URL of each stored object
+type Storage interface {
+	// Put streams r into the object identified by key and returns its public URL.
+	// size may be -1 when the length of r is unknown.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (string, error)
+	// Delete removes the object identified by key
+	Delete(ctx context.Context, key string) error
+	// KeyFromURL returns the object key for a URL produced by Put, or false if
+	// the URL does not belong to this storage
+	KeyFromURL(url string) (string, bool)
+}
+
+// New creates the storage backend selected by cfg.Driver. Files of the local
+// driver are served by this server, so a relative public URL is resolved
+// against appURL and clients always get absolute URLs.
+func New(cfg config.Storage, appURL string) (Storage, error) {
+	switch cfg.Driver {
+	case "", "local":
+		publicURL := cfg.PublicURL
+		if publicURL == "" {
+			publicURL = "/uploads"
+		}
+		if strings.HasPrefix(publicURL, "/") {
+			publicURL = strings.TrimSuffix(appURL, "/") + publicURL
+		}
+		return NewLocalStorage(cfg.LocalDir, publicURL)
+	case "s3":
+		return NewS3Storage(S3Config{
+			Endpoint:  cfg.S3.Endpoint,
+			AccessKey: cfg.S3.AccessKey,
+			SecretKey: cfg.S3.SecretKey.Reveal(),
+			Bucket:    cfg.S3.Bucket,
+			Region:    cfg.S3.Region,
+			UseSSL:    cfg.S3.UseSSL,
+			PublicURL: cfg.PublicURL,
+		})
+	default:
+		return nil, errors.New("unknown STORAGE_DRIVER " + cfg.Driver)
+	}
+}
+
+// NewKey builds a unique object key under prefix with the given extension
+func NewKey(prefix, ext string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(prefix, "/") + "/" + hex.EncodeToString(b) + ext, nil
+}
+
+// PhotoKeyPrefix returns the prefix of the keys of the photos uploaded by userID
+func PhotoKeyPrefix(userID uint) string {
+	return "photos/" + strconv.FormatUint(uint64(userID), 10) + "/"
+}
+
+// IsPhotoKeyOf reports whether key is a photo uploaded by userID. Keys that are
+// not in canonical form, e.g. containing "..", never belong to anyone.
+func IsPhotoKeyOf(key string, userID uint) bool {
+	return path.Clean(key) == key && strings.HasPrefix(key, PhotoKeyPrefix(userID))
+}
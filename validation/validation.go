@@ -0,0 +1,178 @@
+// Package validation checks request structs against their `binding` struct tags.
+//
+// Supported rules, separated by commas:
+//
+//	required   the field must not be the zero value
+//	omitempty  skip the remaining rules when the field is the zero value
+//	email      a bare email address such as "user@example.com"; surrounding spaces are ignored
+//	url        an absolute http or https URL
+//	min=N      at least N characters for strings, N elements for slices, or a value of at least N for numbers
+//	max=N      like min, as an upper bound
+//	oneof=A B  one of the space separated values
+//
+// Pointer fields are zero only when nil, so a pointer to "" is a present value
+// that the other rules check. This lets partial updates tell an omitted field
+// from an empty one.
+package validation
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// FieldError describes a field that failed a rule
+type FieldError struct {
+	// Field is the JSON name of the field
+	Field string `json:"field"`
+	// Rule is the failed rule, e.g. "required" or "min"
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Errors lists every failed field of a struct
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldErr := range e {
+		messages[i] = fieldErr.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Struct validates the exported fields of the struct v (or pointer to struct)
+// and returns nil when all of them pass. It panics on unknown rules, which are
+// programming errors.
+func Struct(v interface{}) error {
+	value := reflect.Indirect(reflect.ValueOf(v))
+	if value.Kind() != reflect.Struct {
+		panic("validation: Struct called with " + value.Kind().String())
+	}
+
+	var errs Errors
+	structType := value.Type()
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		tag, ok := field.Tag.Lookup("binding")
+		if !ok || !field.IsExported() {
+			continue
+		}
+		if fieldErr, failed := checkField(jsonName(field), value.Field(i), tag); failed {
+			errs = append(errs, fieldErr)
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// checkField applies the rules in tag to value and returns the first failure
+func checkField(name string, value reflect.Value, tag string) (FieldError, bool) {
+	zero := value.IsZero()
+	if value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			// Only required applies to an omitted pointer field
+			if strings.Contains(","+tag+",", ",required,") {
+				return FieldError{name, "required", name + " is required"}, true
+			}
+			return FieldError{}, false
+		}
+		value = value.Elem()
+	}
+
+	for _, rule := range strings.Split(tag, ",") {
+		rule, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch rule {
+		case "":
+		case "required":
+			if zero {
+				return FieldError{name, rule, name + " is required"}, true
+			}
+		case "omitempty":
+			if zero {
+				return FieldError{}, false
+			}
+		case "email":
+			if !isEmail(strings.TrimSpace(value.String())) {
+				return FieldError{name, rule, name + " must be a valid email address"}, true
+			}
+		case "url":
+			if !IsURL(value.String()) {
+				return FieldError{name, rule, name + " must be a valid http or https URL"}, true
+			}
+		case "oneof":
+			if !isOneOf(fmt.Sprint(value.Interface()), param) {
+				return FieldError{name, rule, fmt.Sprintf("%s must be one of: %s", name, strings.ReplaceAll(param, " ", ", "))}, true
+			}
+		case "min", "max":
+			limit, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				panic(fmt.Sprintf("validation: invalid %s parameter %q on %s", rule, param, name))
+			}
+			size, unit := measure(value)
+			if rule == "min" && size < limit {
+				return FieldError{name, rule, fmt.Sprintf("%s must be at least %s%s", name, param, unit)}, true
+			}
+			if rule == "max" && size > limit {
+				return FieldError{name, rule, fmt.Sprintf("%s must be at most %s%s", name, param, unit)}, true
+			}
+		default:
+			panic(fmt.Sprintf("validation: unknown rule %q on %s", rule, name))
+		}
+	}
+	return FieldError{}, false
+}
+
+// measure returns the value compared by min and max and the unit used in messages
+func measure(value reflect.Value) (float64, string) {
+	switch value.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(value.String())), " characters"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(value.Len()), " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), ""
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), ""
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), ""
+	default:
+		panic("validation: min and max are not supported for " + value.Kind().String())
+	}
+}
+
+func isOneOf(s, values string) bool {
+	for _, value := range strings.Fields(values) {
+		if s == value {
+			return true
+		}
+	}
+	return false
+}
+
+func isEmail(s string) bool {
+	addr, err := mail.ParseAddress(s)
+	return err == nil && addr.Address == s && strings.Contains(s[strings.LastIndex(s, "@"):], ".")
+}
+
+// IsURL reports whether s is an absolute http or https URL, as checked by the url rule
+func IsURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// jsonName returns the name of field in JSON documents
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}